@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anveesa/proxera/crypto"
+	"github.com/anveesa/proxera/database"
+	"github.com/anveesa/proxera/models"
+	"github.com/google/uuid"
+)
+
+// APIKeyPrefix marks a bearer token as an API key rather than a session.
+const APIKeyPrefix = "pxk_"
+
+// lastUsedGranularity limits how often LastUsedAt is written back, so a
+// busy pipeline doesn't turn every request into a DB write.
+const lastUsedGranularity = time.Minute
+
+var ErrInvalidAPIKey = errors.New("invalid, expired or revoked API key")
+
+// IsAPIKey reports whether token looks like an API key.
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// ValidScope reports whether scope names a known permission.
+func ValidScope(scope string) bool {
+	return rolePermissions[models.RoleAdmin][Permission(scope)]
+}
+
+// CreateAPIKey issues a key for user limited to scopes, each of which the
+// user's own role must grant. It returns the plaintext key.
+func CreateAPIKey(user *models.User, name string, scopes []string, expiresAt *time.Time) (string, *models.APIKey, error) {
+	for _, s := range scopes {
+		if !ValidScope(s) {
+			return "", nil, fmt.Errorf("unknown scope %q", s)
+		}
+		if !HasPermission(user.Role, Permission(s)) {
+			return "", nil, fmt.Errorf("your role cannot grant scope %q", s)
+		}
+	}
+
+	secret, err := crypto.RandomToken(24)
+	if err != nil {
+		return "", nil, err
+	}
+	plaintext := APIKeyPrefix + secret
+
+	b, _ := json.Marshal(scopes)
+	key := models.APIKey{
+		ID:         uuid.New().String(),
+		UserID:     user.ID,
+		Name:       name,
+		Prefix:     plaintext[:len(APIKeyPrefix)+6],
+		KeyHash:    crypto.HashToken(plaintext),
+		ScopesJSON: string(b),
+		ExpiresAt:  expiresAt,
+	}
+	if err := database.DB.Create(&key).Error; err != nil {
+		return "", nil, err
+	}
+	DecodeScopes(&key)
+	return plaintext, &key, nil
+}
+
+// AuthenticateAPIKey resolves an API key to its owner and records its use.
+func AuthenticateAPIKey(token string) (*models.User, *models.APIKey, error) {
+	var key models.APIKey
+	if err := database.DB.Where("key_hash = ?", crypto.HashToken(token)).First(&key).Error; err != nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	now := time.Now()
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && now.After(*key.ExpiresAt)) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", key.UserID).First(&user).Error; err != nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	DecodeServerTags(&user)
+	DecodeScopes(&key)
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedGranularity {
+		database.DB.Model(&key).Update("last_used_at", now)
+		key.LastUsedAt = &now
+	}
+	return &user, &key, nil
+}
+
+// KeyAllows reports whether key carries scope perm.
+func KeyAllows(key *models.APIKey, perm Permission) bool {
+	for _, s := range key.Scopes {
+		if Permission(s) == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// DecodeScopes fills APIKey.Scopes from its stored JSON column.
+func DecodeScopes(k *models.APIKey) {
+	if k.ScopesJSON != "" {
+		json.Unmarshal([]byte(k.ScopesJSON), &k.Scopes) //nolint:errcheck
+	}
+	if k.Scopes == nil {
+		k.Scopes = []string{}
+	}
+}
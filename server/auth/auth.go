@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/anveesa/proxera/crypto"
+	"github.com/anveesa/proxera/database"
+	"github.com/anveesa/proxera/models"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrInvalidToken       = errors.New("invalid or expired session")
+)
+
+// HashPassword returns a bcrypt hash of password.
+func HashPassword(password string) (string, error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// CheckPassword reports whether password matches the bcrypt hash.
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// dummyHash is checked against when no account has the email given to
+// Login, so that an unknown email takes as long to reject as a wrong
+// password and does not reveal which accounts exist. It is hashed at
+// startup so the first such login is not slower either.
+var dummyHash, _ = HashPassword("proxera-no-such-user")
+
+// Login verifies credentials and opens a session lasting ttl. The returned
+// token has the form "<secret>.<signature>".
+func Login(email, password, ip, userAgent string, ttl time.Duration) (string, *models.Session, *models.User, error) {
+	var user models.User
+	if err := database.DB.Where("email = ?", strings.ToLower(strings.TrimSpace(email))).First(&user).Error; err != nil {
+		CheckPassword(dummyHash, password)
+		return "", nil, nil, ErrInvalidCredentials
+	}
+	if !CheckPassword(user.PasswordHash, password) {
+		return "", nil, nil, ErrInvalidCredentials
+	}
+
+	secret, err := crypto.RandomToken(32)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	sig, err := crypto.Sign(secret)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	now := time.Now()
+	session := models.Session{
+		ID:        crypto.HashToken(secret),
+		UserID:    user.ID,
+		IPAddress: ip,
+		UserAgent: userAgent,
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := database.DB.Create(&session).Error; err != nil {
+		return "", nil, nil, err
+	}
+
+	DecodeServerTags(&user)
+	user.LastLogin = &now
+	database.DB.Model(&user).Update("last_login", now)
+	database.DB.Where("user_id = ? AND expires_at < ?", user.ID, now).Delete(&models.Session{})
+
+	return secret + "." + sig, &session, &user, nil
+}
+
+// Authenticate resolves a session token to its user.
+func Authenticate(token string) (*models.User, *models.Session, error) {
+	secret, sig, ok := strings.Cut(token, ".")
+	if !ok || !crypto.Verify(secret, sig) {
+		return nil, nil, ErrInvalidToken
+	}
+
+	var session models.Session
+	if err := database.DB.Where("id = ?", crypto.HashToken(secret)).First(&session).Error; err != nil {
+		return nil, nil, ErrInvalidToken
+	}
+	if time.Now().After(session.ExpiresAt) {
+		database.DB.Delete(&session)
+		return nil, nil, ErrInvalidToken
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", session.UserID).First(&user).Error; err != nil {
+		return nil, nil, ErrInvalidToken
+	}
+	DecodeServerTags(&user)
+	return &user, &session, nil
+}
+
+// Logout revokes the session identified by token.
+func Logout(token string) error {
+	secret, _, _ := strings.Cut(token, ".")
+	return database.DB.Where("id = ?", crypto.HashToken(secret)).Delete(&models.Session{}).Error
+}
+
+// Bootstrap creates the initial admin account when no users exist. If
+// password is empty a random one is generated and logged once. An existing
+// bootstrap account is promoted back to admin if no admin is left.
+func Bootstrap(email, password string) error {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	var admins int64
+	if err := database.DB.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&admins).Error; err != nil {
+		return err
+	}
+	if admins > 0 {
+		return nil
+	}
+	var count int64
+	if err := database.DB.Model(&models.User{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		res := database.DB.Model(&models.User{}).Where("email = ?", email).Update("role", models.RoleAdmin)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected > 0 {
+			log.Printf("No admin account found; promoted %s to admin", email)
+		}
+		return nil
+	}
+
+	generated := false
+	if password == "" {
+		p, err := crypto.RandomToken(12)
+		if err != nil {
+			return err
+		}
+		password = p
+		generated = true
+	}
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	user := models.User{
+		ID:           uuid.New().String(),
+		Name:         "Administrator",
+		Email:        email,
+		PasswordHash: hash,
+		Role:         models.RoleAdmin,
+	}
+	if err := database.DB.Create(&user).Error; err != nil {
+		return fmt.Errorf("create bootstrap admin: %w", err)
+	}
+
+	if generated {
+		log.Printf("Created bootstrap admin %s with generated password: %s (change it after first login)", user.Email, password)
+	} else {
+		log.Printf("Created bootstrap admin %s", user.Email)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"encoding/json"
+
+	"github.com/anveesa/proxera/models"
+)
+
+// Permission names an operation class. They double as API key scopes.
+type Permission string
+
+const (
+	PermServersRead  Permission = "servers:read"
+	PermServersWrite Permission = "servers:write"
+	PermConfigRead   Permission = "config:read"
+	PermConfigWrite  Permission = "config:write" // put config, reload, route sync, backend server state
+	PermRoutesRead   Permission = "routes:read"
+	PermRoutesWrite  Permission = "routes:write"
+	PermAlertsRead   Permission = "alerts:read"
+	PermAlertsWrite  Permission = "alerts:write"
+	PermMetricsRead  Permission = "metrics:read"
+	PermLogsRead     Permission = "logs:read"
+	PermUsersManage  Permission = "users:manage"
+	PermAuditRead    Permission = "audit:read"
+	// PermServersHost covers server settings that decide what runs as root
+	// on a proxy host, or which files Proxera reads there.
+	PermServersHost Permission = "servers:host"
+)
+
+var viewerPerms = []Permission{
+	PermServersRead, PermRoutesRead, PermAlertsRead, PermMetricsRead, PermLogsRead,
+}
+
+var operatorPerms = append([]Permission{
+	PermServersWrite, PermConfigRead, PermConfigWrite, PermRoutesWrite, PermAlertsWrite,
+}, viewerPerms...)
+
+var adminPerms = append([]Permission{PermUsersManage, PermAuditRead, PermServersHost}, operatorPerms...)
+
+var rolePermissions = map[models.UserRole]map[Permission]bool{
+	models.RoleViewer:   permSet(viewerPerms),
+	models.RoleOperator: permSet(operatorPerms),
+	models.RoleAdmin:    permSet(adminPerms),
+}
+
+func permSet(perms []Permission) map[Permission]bool {
+	m := make(map[Permission]bool, len(perms))
+	for _, p := range perms {
+		m[p] = true
+	}
+	return m
+}
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role models.UserRole) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission reports whether role grants perm.
+func HasPermission(role models.UserRole, perm Permission) bool {
+	return rolePermissions[role][perm]
+}
+
+// CanManageServer reports whether user may modify a server with the given
+// tags. Admins and unscoped operators may manage every server; scoped
+// operators only those sharing at least one tag with their scope.
+func CanManageServer(user *models.User, serverTags []string) bool {
+	if user == nil {
+		return false
+	}
+	if user.Role == models.RoleAdmin || len(user.ServerTags) == 0 {
+		return true
+	}
+	for _, want := range user.ServerTags {
+		for _, have := range serverTags {
+			if want == have {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DecodeServerTags fills User.ServerTags from its stored JSON column.
+func DecodeServerTags(u *models.User) {
+	if u.ServerTagsJSON != "" {
+		json.Unmarshal([]byte(u.ServerTagsJSON), &u.ServerTags) //nolint:errcheck
+	}
+	if u.ServerTags == nil {
+		u.ServerTags = []string{}
+	}
+}
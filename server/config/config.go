@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -16,6 +17,24 @@ type Config struct {
 	EncryptionKey []byte
 	AllowOrigins  string
 	Environment   string
+
+	HealthCheckInterval    time.Duration
+	HealthCheckConcurrency int
+
+	MetricsInterval  time.Duration
+	MetricsRetention time.Duration
+
+	MetricsStreamInterval time.Duration
+
+	AnalyticsRetention time.Duration
+
+	// TraefikLocalRoot is the only directory Traefik servers may use as a
+	// local file provider directory; empty disables local file access.
+	TraefikLocalRoot string
+
+	SessionTTL    time.Duration
+	AdminEmail    string
+	AdminPassword string
 }
 
 var C *Config
@@ -45,6 +64,22 @@ func Load() {
 		EncryptionKey: keyBytes,
 		AllowOrigins:  getEnv("ALLOW_ORIGINS", "http://localhost:5173"),
 		Environment:   getEnv("ENVIRONMENT", "development"),
+
+		HealthCheckInterval:    getEnvSeconds("HEALTH_CHECK_INTERVAL", 30),
+		HealthCheckConcurrency: getEnvInt("HEALTH_CHECK_CONCURRENCY", 8),
+
+		MetricsInterval:  getEnvSeconds("METRICS_INTERVAL", 15),
+		MetricsRetention: time.Duration(getEnvInt("METRICS_RETENTION_DAYS", 90)) * 24 * time.Hour,
+
+		MetricsStreamInterval: getEnvSeconds("METRICS_STREAM_INTERVAL", 2),
+
+		AnalyticsRetention: time.Duration(getEnvInt("ANALYTICS_RETENTION_DAYS", 30)) * 24 * time.Hour,
+
+		TraefikLocalRoot: os.Getenv("TRAEFIK_LOCAL_ROOT"),
+
+		SessionTTL:    time.Duration(getEnvInt("SESSION_TTL_HOURS", 24)) * time.Hour,
+		AdminEmail:    getEnv("ADMIN_EMAIL", "admin@proxera.local"),
+		AdminPassword: os.Getenv("ADMIN_PASSWORD"),
 	}
 
 	fmt.Printf("Proxera backend starting on :%s (env=%s)\n", C.Port, C.Environment)
@@ -56,3 +91,20 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvInt(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		log.Fatalf("%s must be a positive integer (got %q)", key, v)
+	}
+	return n
+}
+
+// getEnvSeconds reads a positive number of seconds and returns it as a duration.
+func getEnvSeconds(key string, fallback int) time.Duration {
+	return time.Duration(getEnvInt(key, fallback)) * time.Second
+}
@@ -3,8 +3,11 @@ package crypto
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"io"
 )
@@ -74,3 +77,40 @@ func MaskToken(token string) string {
 	}
 	return "***" + token[len(token)-4:]
 }
+
+// Sign returns a hex HMAC-SHA256 of msg under a key derived from the
+// encryption key, so signatures never reuse the AES key directly.
+func Sign(msg string) (string, error) {
+	if len(globalKey) == 0 {
+		return "", ErrKeyNotSet
+	}
+	derive := hmac.New(sha256.New, globalKey)
+	derive.Write([]byte("proxera-signing-key"))
+	mac := hmac.New(sha256.New, derive.Sum(nil))
+	mac.Write([]byte(msg))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Verify reports whether sig is a valid Sign signature of msg.
+func Verify(msg, sig string) bool {
+	expected, err := Sign(msg)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(expected), []byte(sig))
+}
+
+// RandomToken returns n random bytes, hex-encoded.
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HashToken returns the hex SHA-256 of a bearer secret for storage.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
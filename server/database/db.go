@@ -42,6 +42,14 @@ func Init(dsn string) error {
 		&models.Server{},
 		&models.Route{},
 		&models.Alert{},
+		&models.MetricSample{},
+		&models.AnalyticsBucket{},
+		&models.User{},
+		&models.Session{},
+		&models.APIKey{},
+		&models.AuditEvent{},
+		&models.ConfigRevision{},
+		&models.SSHHostKey{},
 	); err != nil {
 		return fmt.Errorf("automigrate failed: %w", err)
 	}
@@ -0,0 +1,238 @@
+// Package diff produces line-based unified diffs of configuration text.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change.
+const DefaultContext = 3
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+type op struct {
+	kind opKind
+	a, b int // line index in a (equal/delete) and b (equal/insert)
+}
+
+// Unified returns a unified diff turning a into b, labelled with the given
+// file names. It returns "" when the inputs are identical.
+func Unified(fromName, toName, a, b string, context int) string {
+	al, bl := splitLines(a), splitLines(b)
+	ops := edits(al, bl)
+
+	changed := false
+	for _, o := range ops {
+		if o.kind != opEqual {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	for _, h := range hunks(ops, context) {
+		writeHunk(&sb, h, al, bl)
+	}
+	return sb.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.Split(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// edits computes a shortest edit script with Myers' algorithm, after
+// trimming the common prefix and suffix that dominate config edits.
+func edits(a, b []string) []op {
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	var ops []op
+	for i := 0; i < pre; i++ {
+		ops = append(ops, op{opEqual, i, i})
+	}
+	for _, o := range myers(a[pre:len(a)-suf], b[pre:len(b)-suf]) {
+		o.a += pre
+		o.b += pre
+		ops = append(ops, o)
+	}
+	for i := 0; i < suf; i++ {
+		ops = append(ops, op{opEqual, len(a) - suf + i, len(b) - suf + i})
+	}
+	return ops
+}
+
+func myers(a, b []string) []op {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+	offset := max
+	v := make([]int, 2*max+2)
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		snapshot := make([]int, len(v))
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, offset)
+			}
+		}
+	}
+	return nil
+}
+
+func backtrack(trace [][]int, a, b []string, offset int) []op {
+	x, y := len(a), len(b)
+	var rev []op
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			rev = append(rev, op{opEqual, x, y})
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			y--
+			rev = append(rev, op{opInsert, x, y})
+		} else {
+			x--
+			rev = append(rev, op{opDelete, x, y})
+		}
+	}
+
+	ops := make([]op, len(rev))
+	for i, o := range rev {
+		ops[len(rev)-1-i] = o
+	}
+	return ops
+}
+
+// hunks groups ops into runs of changes padded with up to context
+// unchanged lines, merging runs whose padding would overlap.
+func hunks(ops []op, context int) [][]op {
+	var out [][]op
+	start, end := -1, -1
+	for i, o := range ops {
+		if o.kind == opEqual {
+			continue
+		}
+		lo := i - context
+		if lo < 0 {
+			lo = 0
+		}
+		hi := i + context + 1
+		if hi > len(ops) {
+			hi = len(ops)
+		}
+		if start >= 0 && lo <= end {
+			end = hi
+			continue
+		}
+		if start >= 0 {
+			out = append(out, ops[start:end])
+		}
+		start, end = lo, hi
+	}
+	if start >= 0 {
+		out = append(out, ops[start:end])
+	}
+	return out
+}
+
+func writeHunk(sb *strings.Builder, h []op, a, b []string) {
+	aStart, bStart := -1, -1
+	aLen, bLen := 0, 0
+	for _, o := range h {
+		if o.kind != opInsert {
+			if aStart < 0 {
+				aStart = o.a
+			}
+			aLen++
+		}
+		if o.kind != opDelete {
+			if bStart < 0 {
+				bStart = o.b
+			}
+			bLen++
+		}
+	}
+	// An empty side is reported at the line before the change, per the
+	// unified format; a non-empty side is 1-based.
+	if aStart < 0 {
+		aStart = h[0].a - 1
+	}
+	if bStart < 0 {
+		bStart = h[0].b - 1
+	}
+
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(aStart, aLen), hunkRange(bStart, bLen))
+	for _, o := range h {
+		switch o.kind {
+		case opEqual:
+			sb.WriteString(" " + a[o.a] + "\n")
+		case opDelete:
+			sb.WriteString("-" + a[o.a] + "\n")
+		case opInsert:
+			sb.WriteString("+" + b[o.b] + "\n")
+		}
+	}
+}
+
+func hunkRange(start, length int) string {
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
@@ -0,0 +1,82 @@
+package diff
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// numbered returns lines 1 to 12, one per line, with the given lines
+// replaced.
+func numbered(replace map[int]string) string {
+	var b strings.Builder
+	for i := 1; i <= 12; i++ {
+		line, ok := replace[i]
+		if !ok {
+			line = strconv.Itoa(i)
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    string // without the file header
+	}{
+		{"both empty", "", "", 3, ""},
+		{"identical", "a\nb\n", "a\nb\n", 3, ""},
+		{"missing final newline", "a\nb", "a\nb\n", 3, ""},
+
+		{"from empty", "", "x\ny\n", 3, "@@ -0,0 +1,2 @@\n+x\n+y\n"},
+		{"to empty", "x\ny\n", "", 3, "@@ -1,2 +0,0 @@\n-x\n-y\n"},
+		{"insert only", "a\nc\n", "a\nb\nc\n", 3, "@@ -1,2 +1,3 @@\n a\n+b\n c\n"},
+		{"insert without context", "a\nc\n", "a\nb\nc\n", 0, "@@ -1,0 +2 @@\n+b\n"},
+		{"delete only", "a\nb\nc\nd\n", "a\nd\n", 3, "@@ -1,4 +1,2 @@\n a\n-b\n-c\n d\n"},
+		{"delete without context", "a\nb\nc\nd\n", "a\nd\n", 0, "@@ -2,2 +1,0 @@\n-b\n-c\n"},
+		{"change", "a\nb\nc\n", "a\nB\nc\n", 1, "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"},
+
+		{"two hunks", numbered(nil), numbered(map[int]string{2: "two", 11: "eleven"}), 3,
+			"@@ -1,5 +1,5 @@\n 1\n-2\n+two\n 3\n 4\n 5\n" +
+				"@@ -8,5 +8,5 @@\n 8\n 9\n 10\n-11\n+eleven\n 12\n"},
+		{"gap one over twice the context", numbered(nil), numbered(map[int]string{2: "two", 10: "ten"}), 3,
+			"@@ -1,5 +1,5 @@\n 1\n-2\n+two\n 3\n 4\n 5\n" +
+				"@@ -7,6 +7,6 @@\n 7\n 8\n 9\n-10\n+ten\n 11\n 12\n"},
+		{"gap of twice the context merges", numbered(nil), numbered(map[int]string{2: "two", 9: "nine"}), 3,
+			"@@ -1,12 +1,12 @@\n 1\n-2\n+two\n 3\n 4\n 5\n 6\n 7\n 8\n-9\n+nine\n 10\n 11\n 12\n"},
+		{"overlapping context merges", numbered(nil), numbered(map[int]string{2: "two", 8: "eight"}), 3,
+			"@@ -1,11 +1,11 @@\n 1\n-2\n+two\n 3\n 4\n 5\n 6\n 7\n-8\n+eight\n 9\n 10\n 11\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Unified("a.conf", "b.conf", tt.a, tt.b, tt.context)
+			want := tt.want
+			if want != "" {
+				want = "--- a.conf\n+++ b.conf\n" + want
+			}
+			if got != want {
+				t.Errorf("Unified() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestUnifiedShortestEdit(t *testing.T) {
+	// Myers' algorithm keeps the longest common subsequence, so moving one
+	// line costs one delete and one insert, not a rewrite of the block.
+	a := "a\nb\nc\nd\ne\n"
+	b := "b\nc\nd\ne\na\n"
+	got := Unified("x", "y", a, b, 0)
+	n := 0
+	for _, line := range strings.Split(got, "\n")[2:] {
+		if strings.HasPrefix(line, "-") || strings.HasPrefix(line, "+") {
+			n++
+		}
+	}
+	if n != 2 {
+		t.Errorf("Unified() changed %d lines, want 2:\n%s", n, got)
+	}
+}
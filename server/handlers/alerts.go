@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
 	"time"
 
@@ -56,6 +57,7 @@ func CreateAlert(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, "create", "alert", alert.ID, nil, alert)
 	Hub.BroadcastAlert(alert)
 
 	c.JSON(http.StatusCreated, alert)
@@ -82,6 +84,7 @@ func UpdateAlert(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	before := auditSnapshot(alert)
 
 	if req.Status != nil {
 		alert.Status = *req.Status
@@ -100,6 +103,7 @@ func UpdateAlert(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	recordAudit(c, "patch", "alert", alert.ID, before, alert)
 	c.JSON(http.StatusOK, alert)
 }
 
@@ -113,6 +117,7 @@ func DeleteAlert(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	recordAudit(c, "delete", "alert", alert.ID, alert, nil)
 	c.JSON(http.StatusOK, gin.H{"message": "alert deleted"})
 }
 
@@ -122,11 +127,13 @@ func AcknowledgeAlert(c *gin.Context) {
 	if !ok {
 		return
 	}
+	before := auditSnapshot(alert)
 	alert.Status = models.AlertStatusAcknowledged
 	if err := database.DB.Save(alert).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	recordAudit(c, "acknowledge", "alert", alert.ID, before, alert)
 	c.JSON(http.StatusOK, alert)
 }
 
@@ -136,6 +143,7 @@ func ResolveAlert(c *gin.Context) {
 	if !ok {
 		return
 	}
+	before := auditSnapshot(alert)
 	now := time.Now()
 	alert.Status = models.AlertStatusResolved
 	alert.ResolvedAt = &now
@@ -143,6 +151,7 @@ func ResolveAlert(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	recordAudit(c, "resolve", "alert", alert.ID, before, alert)
 	c.JSON(http.StatusOK, alert)
 }
 
@@ -160,6 +169,9 @@ func BulkAcknowledgeAlerts(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	for _, id := range req.IDs {
+		recordAudit(c, "acknowledge", "alert", id, nil, map[string]interface{}{"status": models.AlertStatusAcknowledged})
+	}
 	c.JSON(http.StatusOK, gin.H{"acknowledged": len(req.IDs)})
 }
 
@@ -174,3 +186,22 @@ func findAlert(c *gin.Context) (*models.Alert, bool) {
 	}
 	return &alert, true
 }
+
+// raiseAlert stores and broadcasts an alert generated by Proxera itself.
+func raiseAlert(server *models.Server, severity models.AlertSeverity, category models.AlertCategory, title, message string) {
+	alert := models.Alert{
+		ID:         uuid.New().String(),
+		ServerID:   server.ID,
+		ServerName: server.Name,
+		Severity:   severity,
+		Status:     models.AlertStatusActive,
+		Title:      title,
+		Message:    message,
+		Category:   category,
+	}
+	if err := database.DB.Create(&alert).Error; err != nil {
+		log.Printf("Alerts: raise %q for %s: %v", title, server.Name, err)
+		return
+	}
+	Hub.BroadcastAlert(alert)
+}
@@ -0,0 +1,570 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anveesa/proxera/database"
+	"github.com/anveesa/proxera/models"
+	"github.com/anveesa/proxera/proxy"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	analyticsBucketWidth      = 5 * time.Minute
+	analyticsFlushInterval    = 30 * time.Second
+	analyticsRetryDelay       = 30 * time.Second
+	analyticsUnsupportedDelay = 5 * time.Minute
+	analyticsMaxPoints        = 2000
+
+	// Per bucket, only the busiest endpoints and hosts are kept.
+	analyticsTopEndpoints = 100
+	analyticsTopHosts     = 50
+
+	analyticsReportTop = 20
+)
+
+// analyticsIngester tails every server's logs through its adapter, parses
+// the access log lines and aggregates them into time buckets, which are
+// merged into the database on every flush.
+type analyticsIngester struct {
+	mu      sync.Mutex
+	tailers map[string]*logTailer              // by server ID
+	pending map[string]*models.AnalyticsBucket // by server ID and bucket start
+
+	flushMu sync.Mutex
+}
+
+type logTailer struct {
+	cancel context.CancelFunc
+	source string // logSourceKey when started
+}
+
+var ingester = &analyticsIngester{
+	tailers: make(map[string]*logTailer),
+	pending: make(map[string]*models.AnalyticsBucket),
+}
+
+// StartAnalyticsIngester launches the loop that keeps one log tail per
+// server running, flushes the aggregated buckets and drops buckets older
+// than retention.
+func StartAnalyticsIngester(retention time.Duration) {
+	go func() {
+		ticker := time.NewTicker(analyticsFlushInterval)
+		defer ticker.Stop()
+		lastPrune := time.Time{}
+		for {
+			ingester.sync()
+			ingester.flush()
+			if time.Since(lastPrune) >= metricsPruneInterval {
+				pruneAnalytics(retention)
+				lastPrune = time.Now()
+			}
+			<-ticker.C
+		}
+	}()
+	log.Printf("Analytics ingester started (bucket=%s, retention=%s)", analyticsBucketWidth, retention)
+}
+
+// sync starts a tail for servers that have none, restarts tails whose log
+// source settings changed and stops tails of deleted servers.
+func (in *analyticsIngester) sync() {
+	var servers []models.Server
+	if err := database.DB.Where("deleted_at IS NULL").Find(&servers).Error; err != nil {
+		log.Printf("Analytics ingester: list servers: %v", err)
+		return
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	seen := make(map[string]bool, len(servers))
+	for i := range servers {
+		server := &servers[i]
+		seen[server.ID] = true
+		source := logSourceKey(server)
+		if t, ok := in.tailers[server.ID]; ok {
+			if t.source == source {
+				continue
+			}
+			t.cancel()
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		in.tailers[server.ID] = &logTailer{cancel: cancel, source: source}
+		go in.tail(ctx, server.ID)
+	}
+	for id, t := range in.tailers {
+		if !seen[id] {
+			t.cancel()
+			delete(in.tailers, id)
+		}
+	}
+}
+
+// tail keeps a server's log stream open until ctx ends, reconnecting after
+// errors. Adapters that cannot tail logs are asked again now and then.
+func (in *analyticsIngester) tail(ctx context.Context, serverID string) {
+	for {
+		delay := in.tailOnce(ctx, serverID)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// tailOnce reads one log stream to its end and returns how long to wait
+// before the next. Lines logged before the stream was opened (tail and
+// docker print some history) are skipped, so reconnecting does not count
+// them twice. Requests are bucketed by when they were read.
+func (in *analyticsIngester) tailOnce(ctx context.Context, serverID string) time.Duration {
+	var server models.Server
+	if err := database.DB.Where("id = ? AND deleted_at IS NULL", serverID).First(&server).Error; err != nil {
+		return analyticsRetryDelay
+	}
+	adapter, err := buildAdapter(&server)
+	if err != nil {
+		return analyticsRetryDelay
+	}
+	rc, err := adapter.TailLogs(ctx)
+	var unsupported *proxy.ErrNotSupported
+	if errors.As(err, &unsupported) {
+		return analyticsUnsupportedDelay
+	}
+	if err != nil {
+		return analyticsRetryDelay
+	}
+	defer rc.Close()
+
+	opened := time.Now().Truncate(time.Second)
+	scanner := bufio.NewScanner(rc)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		e, ok := proxy.ParseAccessLog(server.ProxyType, scanner.Text())
+		if !ok || (!e.Timestamp.IsZero() && e.Timestamp.Before(opened)) {
+			continue
+		}
+		in.add(server.ID, e, time.Now())
+	}
+	return analyticsRetryDelay
+}
+
+// add counts one request into the pending bucket for at.
+func (in *analyticsIngester) add(serverID string, e *models.LogEntry, at time.Time) {
+	start := at.UTC().Truncate(analyticsBucketWidth)
+	key := serverID + "|" + strconv.FormatInt(start.Unix(), 10)
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	b, ok := in.pending[key]
+	if !ok {
+		b = newAnalyticsBucket(serverID, start)
+		in.pending[key] = b
+	}
+
+	b.Requests++
+	switch e.Status / 100 {
+	case 1:
+		b.Status1xx++
+	case 2:
+		b.Status2xx++
+	case 3:
+		b.Status3xx++
+	case 4:
+		b.Status4xx++
+	case 5:
+		b.Status5xx++
+	}
+	b.BytesSent += e.Bytes
+	if e.Duration != nil {
+		b.LatencyCount++
+		b.LatencySum += *e.Duration
+		b.Latency[latencyBucket(*e.Duration)]++
+	}
+	if e.Path != "" {
+		name := e.Method + " " + normalizeEndpointPath(e.Path)
+		ep, ok := b.Endpoints[name]
+		if !ok {
+			ep = &models.EndpointCounts{}
+			b.Endpoints[name] = ep
+		}
+		ep.Requests++
+		if e.Status >= 500 {
+			ep.Errors++
+		}
+		if e.Duration != nil {
+			ep.LatencyCount++
+			ep.LatencySum += *e.Duration
+		}
+	}
+	if e.Host != "" {
+		b.Hosts[strings.ToLower(e.Host)]++
+	}
+}
+
+// flush merges the pending buckets into their stored rows.
+func (in *analyticsIngester) flush() {
+	in.flushMu.Lock()
+	defer in.flushMu.Unlock()
+
+	in.mu.Lock()
+	pending := in.pending
+	in.pending = make(map[string]*models.AnalyticsBucket)
+	in.mu.Unlock()
+
+	for _, b := range pending {
+		if err := storeAnalyticsBucket(b); err != nil {
+			log.Printf("Analytics ingester: store bucket for server %s: %v", b.ServerID, err)
+		}
+	}
+}
+
+func storeAnalyticsBucket(b *models.AnalyticsBucket) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		var row models.AnalyticsBucket
+		if err := tx.Where("server_id = ? AND start = ?", b.ServerID, b.Start).Limit(1).Find(&row).Error; err != nil {
+			return err
+		}
+		if row.ID == 0 {
+			row = *b
+		} else {
+			decodeAnalyticsBucket(&row)
+			mergeAnalyticsBucket(&row, b)
+		}
+		encodeAnalyticsBucket(&row)
+		return tx.Save(&row).Error
+	})
+}
+
+func pruneAnalytics(retention time.Duration) {
+	cutoff := time.Now().UTC().Add(-retention)
+	if err := database.DB.Where("start < ?", cutoff).Delete(&models.AnalyticsBucket{}).Error; err != nil {
+		log.Printf("Analytics ingester: prune buckets: %v", err)
+	}
+}
+
+// GetAnalytics GET /api/v1/analytics
+//
+// Query: serverId (default all servers), since and until (RFC3339; default
+// the last 24 hours) and step, the series resolution as a duration
+// (default 1h, or 24h for ranges over two days).
+func GetAnalytics(c *gin.Context) {
+	until := time.Now().UTC()
+	if u := c.Query("until"); u != "" {
+		t, err := time.Parse(time.RFC3339, u)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until must be RFC3339"})
+			return
+		}
+		until = t.UTC()
+	}
+	since := until.Add(-24 * time.Hour)
+	if s := c.Query("since"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be RFC3339"})
+			return
+		}
+		since = t.UTC()
+	}
+	if !since.Before(until) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "since must be before until"})
+		return
+	}
+	step := time.Hour
+	if until.Sub(since) > 48*time.Hour {
+		step = 24 * time.Hour
+	}
+	if s := c.Query("step"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < analyticsBucketWidth {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "step must be a duration of at least " + analyticsBucketWidth.String()})
+			return
+		}
+		step = d
+	}
+	if until.Sub(since)/step > analyticsMaxPoints {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "step is too small for this range"})
+		return
+	}
+
+	// Make the last half minute of traffic visible.
+	ingester.flush()
+
+	q := database.DB.Where("start >= ? AND start < ?", since.Truncate(analyticsBucketWidth), until)
+	if sid := c.Query("serverId"); sid != "" && sid != "all" {
+		q = q.Where("server_id = ?", sid)
+	}
+	var buckets []models.AnalyticsBucket
+	if err := q.Order("start").Find(&buckets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range buckets {
+		decodeAnalyticsBucket(&buckets[i])
+	}
+
+	c.JSON(http.StatusOK, buildAnalyticsReport(buckets, since, until, step))
+}
+
+// ─── Helpers ──────────────────────────────────────────────────────────────────
+
+func buildAnalyticsReport(buckets []models.AnalyticsBucket, since, until time.Time, step time.Duration) models.AnalyticsReport {
+	report := models.AnalyticsReport{Since: since, Until: until}
+
+	total := newAnalyticsBucket("", since)
+	perServer := map[string]*models.AnalyticsBucket{}
+	points := make([]*models.AnalyticsBucket, (until.Sub(since)+step-1)/step)
+	for i := range buckets {
+		b := &buckets[i]
+		mergeAnalyticsBucket(total, b)
+		if perServer[b.ServerID] == nil {
+			perServer[b.ServerID] = newAnalyticsBucket(b.ServerID, since)
+		}
+		mergeAnalyticsBucket(perServer[b.ServerID], b)
+
+		n := 0
+		if b.Start.After(since) {
+			n = int(b.Start.Sub(since) / step)
+		}
+		if n >= len(points) {
+			continue
+		}
+		if points[n] == nil {
+			points[n] = newAnalyticsBucket("", since.Add(time.Duration(n)*step))
+		}
+		mergeAnalyticsBucket(points[n], b)
+	}
+
+	report.Requests = total.Requests
+	report.Errors = total.Status5xx
+	report.ErrorRate = percentOf(total.Status5xx, total.Requests)
+	report.BytesSent = total.BytesSent
+	report.Latency = latencyPercentiles(total)
+
+	for _, s := range []struct {
+		class string
+		count int64
+	}{{"1xx", total.Status1xx}, {"2xx", total.Status2xx}, {"3xx", total.Status3xx}, {"4xx", total.Status4xx}, {"5xx", total.Status5xx}} {
+		report.StatusCodes = append(report.StatusCodes, models.StatusClassCount{
+			Class: s.class, Count: s.count, Percent: percentOf(s.count, total.Requests),
+		})
+	}
+
+	report.TopEndpoints = []models.EndpointStats{}
+	for _, name := range topKeys(total.Endpoints, func(e *models.EndpointCounts) int64 { return e.Requests }, analyticsReportTop) {
+		ep := total.Endpoints[name]
+		stats := models.EndpointStats{Endpoint: name, Requests: ep.Requests, ErrorRate: percentOf(ep.Errors, ep.Requests)}
+		if ep.LatencyCount > 0 {
+			stats.AvgMs = ep.LatencySum / float64(ep.LatencyCount)
+		}
+		report.TopEndpoints = append(report.TopEndpoints, stats)
+	}
+
+	report.TopHosts = []models.HostStats{}
+	for _, host := range topKeys(total.Hosts, func(n int64) int64 { return n }, analyticsReportTop) {
+		report.TopHosts = append(report.TopHosts, models.HostStats{Host: host, Requests: total.Hosts[host]})
+	}
+
+	report.Servers = []models.ServerAnalytics{}
+	for _, id := range topKeys(perServer, func(b *models.AnalyticsBucket) int64 { return b.Requests }, len(perServer)) {
+		b := perServer[id]
+		report.Servers = append(report.Servers, models.ServerAnalytics{
+			ServerID:  id,
+			Requests:  b.Requests,
+			ErrorRate: percentOf(b.Status5xx, b.Requests),
+			Latency:   latencyPercentiles(b),
+		})
+	}
+
+	report.Series = make([]models.AnalyticsPoint, len(points))
+	for i, b := range points {
+		p := models.AnalyticsPoint{Time: since.Add(time.Duration(i) * step)}
+		if b != nil {
+			l := latencyPercentiles(b)
+			p.Requests, p.Errors, p.BytesSent, p.P50, p.P95 = b.Requests, b.Status5xx, b.BytesSent, l.P50, l.P95
+		}
+		report.Series[i] = p
+	}
+	return report
+}
+
+func newAnalyticsBucket(serverID string, start time.Time) *models.AnalyticsBucket {
+	return &models.AnalyticsBucket{
+		ServerID:  serverID,
+		Start:     start,
+		Latency:   make([]int64, len(models.AnalyticsLatencyBounds)+1),
+		Endpoints: make(map[string]*models.EndpointCounts),
+		Hosts:     make(map[string]int64),
+	}
+}
+
+// mergeAnalyticsBucket adds src's counts to dst and trims dst's endpoints
+// and hosts to the busiest ones.
+func mergeAnalyticsBucket(dst, src *models.AnalyticsBucket) {
+	dst.Requests += src.Requests
+	dst.Status1xx += src.Status1xx
+	dst.Status2xx += src.Status2xx
+	dst.Status3xx += src.Status3xx
+	dst.Status4xx += src.Status4xx
+	dst.Status5xx += src.Status5xx
+	dst.BytesSent += src.BytesSent
+	dst.LatencyCount += src.LatencyCount
+	dst.LatencySum += src.LatencySum
+	for i, n := range src.Latency {
+		if i < len(dst.Latency) {
+			dst.Latency[i] += n
+		}
+	}
+	for name, ep := range src.Endpoints {
+		d, ok := dst.Endpoints[name]
+		if !ok {
+			d = &models.EndpointCounts{}
+			dst.Endpoints[name] = d
+		}
+		d.Requests += ep.Requests
+		d.Errors += ep.Errors
+		d.LatencyCount += ep.LatencyCount
+		d.LatencySum += ep.LatencySum
+	}
+	for host, n := range src.Hosts {
+		dst.Hosts[host] += n
+	}
+}
+
+// encodeAnalyticsBucket writes the JSON columns, keeping only the busiest
+// endpoints and hosts.
+func encodeAnalyticsBucket(b *models.AnalyticsBucket) {
+	endpoints := make(map[string]*models.EndpointCounts, analyticsTopEndpoints)
+	for _, name := range topKeys(b.Endpoints, func(e *models.EndpointCounts) int64 { return e.Requests }, analyticsTopEndpoints) {
+		endpoints[name] = b.Endpoints[name]
+	}
+	hosts := make(map[string]int64, analyticsTopHosts)
+	for _, host := range topKeys(b.Hosts, func(n int64) int64 { return n }, analyticsTopHosts) {
+		hosts[host] = b.Hosts[host]
+	}
+	latency, _ := json.Marshal(b.Latency)
+	eps, _ := json.Marshal(endpoints)
+	hs, _ := json.Marshal(hosts)
+	b.LatencyJSON, b.EndpointsJSON, b.HostsJSON = string(latency), string(eps), string(hs)
+}
+
+func decodeAnalyticsBucket(b *models.AnalyticsBucket) {
+	b.Latency = make([]int64, len(models.AnalyticsLatencyBounds)+1)
+	b.Endpoints = make(map[string]*models.EndpointCounts)
+	b.Hosts = make(map[string]int64)
+	json.Unmarshal([]byte(b.LatencyJSON), &b.Latency)     //nolint:errcheck
+	json.Unmarshal([]byte(b.EndpointsJSON), &b.Endpoints) //nolint:errcheck
+	json.Unmarshal([]byte(b.HostsJSON), &b.Hosts)         //nolint:errcheck
+}
+
+// latencyBucket returns the histogram bucket for a duration in ms.
+func latencyBucket(ms float64) int {
+	return sort.SearchFloat64s(models.AnalyticsLatencyBounds, ms)
+}
+
+func latencyPercentiles(b *models.AnalyticsBucket) models.LatencyPercentiles {
+	var l models.LatencyPercentiles
+	if b.LatencyCount > 0 {
+		l.Avg = b.LatencySum / float64(b.LatencyCount)
+	}
+	l.P50 = latencyQuantile(0.50, b.Latency)
+	l.P95 = latencyQuantile(0.95, b.Latency)
+	l.P99 = latencyQuantile(0.99, b.Latency)
+	return l
+}
+
+// latencyQuantile estimates the q-quantile of a latency histogram by
+// interpolating inside the bucket it falls in. Past the last bound it
+// returns that bound.
+func latencyQuantile(q float64, counts []int64) float64 {
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	bounds := models.AnalyticsLatencyBounds
+	rank := q * float64(total)
+	var below, lower float64
+	for i, n := range counts {
+		if i >= len(bounds) {
+			break
+		}
+		if n > 0 && below+float64(n) >= rank {
+			return lower + (bounds[i]-lower)*(rank-below)/float64(n)
+		}
+		below += float64(n)
+		lower = bounds[i]
+	}
+	return bounds[len(bounds)-1]
+}
+
+// topKeys returns up to n keys of m, busiest first, ties by name.
+func topKeys[V any](m map[string]V, count func(V) int64, n int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ci, cj := count(m[keys[i]]), count(m[keys[j]])
+		if ci != cj {
+			return ci > cj
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
+
+func percentOf(part, whole int64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(part) / float64(whole) * 100
+}
+
+// idSegmentRe matches path segments that are identifiers rather than
+// names: numbers, UUIDs and long hex strings.
+var idSegmentRe = regexp.MustCompile(`^(\d+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,})$`)
+
+// normalizeEndpointPath drops the query string and replaces identifier
+// segments with ":id", so /users/42 and /users/43 count as one endpoint.
+func normalizeEndpointPath(p string) string {
+	if i := strings.IndexAny(p, "?#"); i >= 0 {
+		p = p[:i]
+	}
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		if idSegmentRe.MatchString(s) {
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// logSourceKey changes whenever a setting that decides where a server's
+// logs come from does.
+func logSourceKey(s *models.Server) string {
+	b, _ := json.Marshal([]interface{}{
+		s.ProxyType, s.ConnectionType, s.Host, s.Port,
+		s.SSHUser, s.SSHKeyContent, s.SSHPassphraseEnc, s.SSHPasswordEnc,
+		s.JumpHost, s.JumpPort, s.JumpUser, s.JumpKeyEnc, s.JumpPassphraseEnc,
+		s.APIURL, s.APITokenEnc, s.NGINX, s.Traefik, s.HAProxy, s.Logs,
+	})
+	return string(b)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/anveesa/proxera/auth"
+	"github.com/anveesa/proxera/database"
+	"github.com/anveesa/proxera/middleware"
+	"github.com/anveesa/proxera/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ListAPIKeys GET /api/v1/api-keys
+// Admins see every key; everyone else sees their own.
+func ListAPIKeys(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	q := database.DB.Order("created_at DESC")
+	if user.Role != models.RoleAdmin {
+		q = q.Where("user_id = ?", user.ID)
+	}
+
+	var keys []models.APIKey
+	if err := q.Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range keys {
+		auth.DecodeScopes(&keys[i])
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// CreateAPIKey POST /api/v1/api-keys
+func CreateAPIKey(c *gin.Context) {
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expiresAt must be in the future"})
+		return
+	}
+
+	plaintext, key, err := auth.CreateAPIKey(middleware.CurrentUser(c), req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "create", "api_key", key.ID, nil, key)
+	c.JSON(http.StatusCreated, models.CreateAPIKeyResponse{APIKey: *key, Key: plaintext})
+}
+
+// RevokeAPIKey DELETE /api/v1/api-keys/:id
+func RevokeAPIKey(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	var key models.APIKey
+	if err := database.DB.First(&key, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+		return
+	}
+	if key.UserID != user.ID && user.Role != models.RoleAdmin {
+		c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+		return
+	}
+
+	now := time.Now()
+	key.RevokedAt = &now
+	if err := database.DB.Save(&key).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(c, "revoke", "api_key", key.ID, nil, map[string]interface{}{"revokedAt": now})
+	c.JSON(http.StatusOK, gin.H{"message": "api key revoked"})
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anveesa/proxera/database"
+	"github.com/anveesa/proxera/middleware"
+	"github.com/anveesa/proxera/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const redacted = "[REDACTED]"
+
+// auditIgnored lists bookkeeping fields that change on every write.
+var auditIgnored = map[string]bool{"updatedAt": true}
+
+// ListAuditEvents GET /api/v1/audit
+func ListAuditEvents(c *gin.Context) {
+	q := database.DB.Order("created_at DESC")
+
+	if a := c.Query("actor"); a != "" {
+		q = q.Where("actor_id = ? OR actor = ?", a, a)
+	}
+	if a := c.Query("action"); a != "" {
+		q = q.Where("action = ?", a)
+	}
+	if rt := c.Query("resourceType"); rt != "" {
+		q = q.Where("resource_type = ?", rt)
+	}
+	if rid := c.Query("resourceId"); rid != "" {
+		q = q.Where("resource_id = ?", rid)
+	}
+	if s := c.Query("since"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be RFC3339"})
+			return
+		}
+		q = q.Where("created_at >= ?", t)
+	}
+	if u := c.Query("until"); u != "" {
+		t, err := time.Parse(time.RFC3339, u)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until must be RFC3339"})
+			return
+		}
+		q = q.Where("created_at <= ?", t)
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		limit = 100
+	}
+
+	var events []models.AuditEvent
+	if err := q.Limit(limit).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range events {
+		if events[i].DiffJSON != "" {
+			json.Unmarshal([]byte(events[i].DiffJSON), &events[i].Diff) //nolint:errcheck
+		}
+	}
+	c.JSON(http.StatusOK, events)
+}
+
+// recordAudit appends an audit event for the current caller. before and
+// after are any JSON-serialisable snapshots (nil for create/delete sides);
+// only fields that changed are kept, with secrets redacted. Failures are
+// logged rather than failing the already-completed operation.
+func recordAudit(c *gin.Context, action, resourceType, resourceID string, before, after interface{}) {
+	event := models.AuditEvent{
+		ID:           uuid.New().String(),
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		SourceIP:     c.ClientIP(),
+	}
+	if user := middleware.CurrentUser(c); user != nil {
+		event.ActorID = user.ID
+		event.Actor = user.Email
+	}
+	if key := middleware.CurrentAPIKey(c); key != nil {
+		event.APIKeyID = key.ID
+		event.Actor += " (api key " + key.Name + ")"
+	}
+
+	if diff := auditDiff(before, after); len(diff) > 0 {
+		b, _ := json.Marshal(diff)
+		event.DiffJSON = string(b)
+	}
+
+	if err := database.DB.Create(&event).Error; err != nil {
+		log.Printf("Audit: record %s %s/%s: %v", action, resourceType, resourceID, err)
+	}
+}
+
+// auditSnapshot captures v's JSON form at this moment, so later mutations
+// of v don't leak into the "before" side of a diff.
+func auditSnapshot(v interface{}) map[string]interface{} {
+	if v == nil {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var m map[string]interface{}
+	json.Unmarshal(b, &m) //nolint:errcheck
+	return m
+}
+
+func auditDiff(before, after interface{}) map[string]models.AuditChange {
+	b, ok := before.(map[string]interface{})
+	if !ok {
+		b = auditSnapshot(before)
+	}
+	a, ok := after.(map[string]interface{})
+	if !ok {
+		a = auditSnapshot(after)
+	}
+
+	diff := make(map[string]models.AuditChange)
+	for k, bv := range b {
+		if auditIgnored[k] {
+			continue
+		}
+		if av, ok := a[k]; !ok || !reflect.DeepEqual(av, bv) {
+			diff[k] = redactChange(k, bv, a[k])
+		}
+	}
+	for k, av := range a {
+		if _, ok := b[k]; !ok && !auditIgnored[k] {
+			diff[k] = redactChange(k, nil, av)
+		}
+	}
+	return diff
+}
+
+func redactChange(field string, before, after interface{}) models.AuditChange {
+	if !isSecretField(field) {
+		return models.AuditChange{Before: before, After: after}
+	}
+	var ch models.AuditChange
+	if before != nil {
+		ch.Before = redacted
+	}
+	if after != nil {
+		ch.After = redacted
+	}
+	return ch
+}
+
+func isSecretField(field string) bool {
+	f := strings.ToLower(field)
+	return strings.Contains(f, "password") ||
+		strings.Contains(f, "token") ||
+		strings.Contains(f, "secret") ||
+		strings.HasSuffix(f, "key") ||
+		strings.HasSuffix(f, "keyhash")
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/anveesa/proxera/auth"
+	"github.com/anveesa/proxera/config"
+	"github.com/anveesa/proxera/middleware"
+	"github.com/anveesa/proxera/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Login POST /api/v1/auth/login
+func Login(c *gin.Context) {
+	var req models.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, session, user, err := auth.Login(req.Email, req.Password, c.ClientIP(), c.Request.UserAgent(), config.C.SessionTTL)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	secure := config.C.Environment == "production"
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(middleware.SessionCookie, token, int(config.C.SessionTTL.Seconds()), "/", "", secure, true)
+
+	c.JSON(http.StatusOK, models.LoginResponse{
+		Token:     token,
+		ExpiresAt: session.ExpiresAt,
+		User:      *user,
+	})
+}
+
+// Logout POST /api/v1/auth/logout
+func Logout(c *gin.Context) {
+	if err := auth.Logout(middleware.RequestToken(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.SetCookie(middleware.SessionCookie, "", -1, "/", "", config.C.Environment == "production", true)
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+// Me GET /api/v1/auth/me
+func Me(c *gin.Context) {
+	c.JSON(http.StatusOK, middleware.CurrentUser(c))
+}
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"math/rand"
 	"net/http"
 	"strconv"
 	"time"
@@ -33,11 +32,26 @@ func DashboardStats(c *gin.Context) {
 	database.DB.Model(&models.Alert{}).Where("status = ?", "active").Count(&alertCount)
 	stats.ActiveAlerts = int(alertCount)
 
-	// Synthetic aggregates — replace with real metrics store in production
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-	stats.TotalRequestsToday = int64(rng.Intn(1000000) + 100000)
-	stats.AvgErrorRate = rng.Float64() * 2
-	stats.AvgLatency = rng.Float64()*50 + 10
+	// Request aggregates come from today's hourly rollups
+	startOfDay := time.Now().UTC().Truncate(24 * time.Hour)
+	var samples []models.MetricSample
+	database.DB.Where("resolution = ? AND timestamp >= ?", models.ResolutionHour, startOfDay).Find(&samples)
+
+	var requests, errors, latencySum float64
+	var sampleCount int
+	for _, s := range samples {
+		requests += s.Requests
+		errors += s.Errors
+		latencySum += s.P50Latency * float64(s.Samples)
+		sampleCount += s.Samples
+	}
+	stats.TotalRequestsToday = int64(requests)
+	if requests > 0 {
+		stats.AvgErrorRate = errors / requests * 100
+	}
+	if sampleCount > 0 {
+		stats.AvgLatency = latencySum / float64(sampleCount)
+	}
 
 	c.JSON(http.StatusOK, stats)
 }
@@ -50,33 +64,47 @@ func DashboardTraffic(c *gin.Context) {
 		hours = 24
 	}
 
-	now := time.Now().Truncate(time.Hour)
-	points := make([]models.TrafficPoint, hours)
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-
-	baseRequests := 5000
-	for i := hours - 1; i >= 0; i-- {
-		t := now.Add(time.Duration(-i) * time.Hour)
-		hour := t.Hour()
-
-		var multiplier float64
-		switch {
-		case hour >= 9 && hour <= 17:
-			multiplier = 1.0 + rng.Float64()*0.5
-		case hour >= 18 && hour <= 22:
-			multiplier = 0.7 + rng.Float64()*0.3
-		default:
-			multiplier = 0.2 + rng.Float64()*0.2
+	now := time.Now().UTC().Truncate(time.Hour)
+	since := now.Add(time.Duration(-(hours - 1)) * time.Hour)
+
+	var samples []models.MetricSample
+	if err := database.DB.Where("resolution = ? AND timestamp >= ?", models.ResolutionHour, since).
+		Find(&samples).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Rollups are per server; sum them into one fleet-wide point per hour.
+	type bucket struct {
+		requests, errors, latencySum float64
+		samples                      int
+	}
+	buckets := make(map[int64]*bucket, hours)
+	for _, s := range samples {
+		key := s.Timestamp.UTC().Truncate(time.Hour).Unix()
+		b, ok := buckets[key]
+		if !ok {
+			b = &bucket{}
+			buckets[key] = b
 		}
+		b.requests += s.Requests
+		b.errors += s.Errors
+		b.latencySum += s.P50Latency * float64(s.Samples)
+		b.samples += s.Samples
+	}
 
-		requests := int(float64(baseRequests) * multiplier)
-		errorRate := 0.01 + rng.Float64()*0.02
-		points[hours-1-i] = models.TrafficPoint{
-			Time:     t,
-			Requests: requests,
-			Errors:   int(float64(requests) * errorRate),
-			Latency:  20 + rng.Float64()*30,
+	points := make([]models.TrafficPoint, hours)
+	for i := 0; i < hours; i++ {
+		t := since.Add(time.Duration(i) * time.Hour)
+		p := models.TrafficPoint{Time: t}
+		if b, ok := buckets[t.Unix()]; ok {
+			p.Requests = int(b.requests)
+			p.Errors = int(b.errors)
+			if b.samples > 0 {
+				p.Latency = b.latencySum / float64(b.samples)
+			}
 		}
+		points[i] = p
 	}
 
 	c.JSON(http.StatusOK, points)
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/anveesa/proxera/database"
+	"github.com/anveesa/proxera/models"
+	"github.com/anveesa/proxera/proxy"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultGraceSeconds = 10
+	maxGraceSeconds     = 120
+	probeInterval       = 2 * time.Second
+	probeFailThreshold  = 2 // consecutive failed rounds before restoring
+)
+
+var probeClient = &http.Client{
+	Timeout: 5 * time.Second,
+	// Report redirects as-is; following them would probe another host.
+	CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+}
+
+// validateGuard fills in defaults and rejects unusable probes.
+func validateGuard(opts *models.GuardOptions) error {
+	if opts.GraceSeconds <= 0 {
+		opts.GraceSeconds = defaultGraceSeconds
+	}
+	if opts.GraceSeconds > maxGraceSeconds {
+		return fmt.Errorf("graceSeconds must be at most %d", maxGraceSeconds)
+	}
+	for _, p := range opts.Checks {
+		u, err := url.Parse(p.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid check url %q", p.URL)
+		}
+	}
+	return nil
+}
+
+// guardContext bounds a config operation. Guarded ones get room for the
+// grace window and are detached from the request, so a client
+// disconnecting mid-way cannot abandon a restore.
+func guardContext(c *gin.Context, opts *models.GuardOptions) (context.Context, context.CancelFunc) {
+	if opts == nil {
+		return context.WithTimeout(c.Request.Context(), 30*time.Second)
+	}
+	timeout := 60*time.Second + time.Duration(opts.GraceSeconds)*time.Second
+	return context.WithTimeout(context.WithoutCancel(c.Request.Context()), timeout)
+}
+
+// guardedReload reloads the proxy and probes it for the grace window. If
+// it turns unhealthy, snapshot is written back, the proxy reloaded again and
+// a critical config alert raised. An empty snapshot means there is nothing
+// to restore; the alert is raised regardless.
+func guardedReload(ctx context.Context, c *gin.Context, server *models.Server, adapter proxy.ProxyAdapter, snapshot *models.ProxyConfig, opts *models.GuardOptions) *models.GuardResult {
+	result := &models.GuardResult{}
+	if err := adapter.Reload(ctx); err != nil {
+		result.Error = "reload: " + err.Error()
+	} else if watchHealth(ctx, adapter, opts, result) {
+		result.Healthy = true
+		return result
+	}
+	reason := result.Error
+	if reason == "" {
+		reason = "health probes failed: " + failedProbes(result.Probes)
+	}
+
+	title := "Config rolled back on " + server.Name
+	switch {
+	case snapshot == nil:
+		title = "Proxy unhealthy after reload on " + server.Name
+		reason += "; no previous config to restore"
+	case !restoreConfig(ctx, adapter, snapshot, &reason):
+		title = "Config rollback failed on " + server.Name
+	default:
+		result.RolledBack = true
+		recordConfigChange(ctx, c, server, adapter, nil, snapshot, models.RevisionSourceAutoRollback)
+		recordAudit(c, "config_auto_rollback", "server", server.ID, nil, map[string]interface{}{
+			"config": configFingerprint(snapshot),
+			"reason": reason,
+		})
+	}
+	result.Error = reason
+
+	raiseAlert(server, models.SeverityCritical, models.CategoryConfig, title, reason)
+	return result
+}
+
+// restoreConfig writes snapshot back and reloads, appending any failure to
+// reason.
+func restoreConfig(ctx context.Context, adapter proxy.ProxyAdapter, snapshot *models.ProxyConfig, reason *string) bool {
+	v, err := applyConfig(ctx, adapter, snapshot, currentConfig(ctx, adapter))
+	if err != nil {
+		*reason += "; restore failed: " + err.Error()
+		return false
+	}
+	if !v.IsValid {
+		*reason += "; restore failed validation: " + strings.Join(v.Errors, "; ")
+		return false
+	}
+	if err := adapter.Reload(ctx); err != nil {
+		*reason += "; reload after restore failed: " + err.Error()
+		return false
+	}
+	return true
+}
+
+// watchHealth probes the proxy every probeInterval until the grace window
+// ends, reporting false once probeFailThreshold rounds in a row fail.
+func watchHealth(ctx context.Context, adapter proxy.ProxyAdapter, opts *models.GuardOptions, result *models.GuardResult) bool {
+	deadline := time.Now().Add(time.Duration(opts.GraceSeconds) * time.Second)
+	failures := 0
+	for {
+		result.Probes = runProbes(ctx, adapter, opts.Checks)
+		result.Rounds++
+		if allOK(result.Probes) {
+			failures = 0
+		} else {
+			failures++
+			if failures >= probeFailThreshold {
+				return false
+			}
+		}
+
+		if time.Now().Add(probeInterval).After(deadline) {
+			return failures == 0
+		}
+		select {
+		case <-ctx.Done():
+			result.Error = "health probes interrupted: " + ctx.Err().Error()
+			return false
+		case <-time.After(probeInterval):
+		}
+	}
+}
+
+func runProbes(ctx context.Context, adapter proxy.ProxyAdapter, checks []models.HealthProbe) []models.ProbeResult {
+	results := make([]models.ProbeResult, 0, len(checks)+1)
+
+	latency, err := adapter.Ping(ctx)
+	ping := models.ProbeResult{Name: "ping", OK: err == nil, LatencyMs: latency}
+	if err != nil {
+		ping.Error = err.Error()
+	}
+	results = append(results, ping)
+
+	for _, check := range checks {
+		results = append(results, runHTTPProbe(ctx, check))
+	}
+	return results
+}
+
+func runHTTPProbe(ctx context.Context, check models.HealthProbe) models.ProbeResult {
+	res := models.ProbeResult{Name: check.URL}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, check.URL, nil)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if check.Host != "" {
+		req.Host = check.Host
+	}
+
+	start := time.Now()
+	resp, err := probeClient.Do(req)
+	res.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	resp.Body.Close()
+
+	if check.ExpectStatus != 0 {
+		res.OK = resp.StatusCode == check.ExpectStatus
+	} else {
+		res.OK = resp.StatusCode < 500
+	}
+	if !res.OK {
+		res.Error = fmt.Sprintf("HTTP %d", resp.StatusCode)
+	}
+	return res
+}
+
+func allOK(probes []models.ProbeResult) bool {
+	for _, p := range probes {
+		if !p.OK {
+			return false
+		}
+	}
+	return true
+}
+
+func failedProbes(probes []models.ProbeResult) string {
+	var parts []string
+	for _, p := range probes {
+		if !p.OK {
+			parts = append(parts, p.Name+": "+p.Error)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// lastAppliedConfig returns the newest recorded revision if it differs from
+// current, as the restore point for a guarded reload. A reload only picks up
+// what is already on disk, so the config to fall back to is the last one we
+// know of rather than the current one.
+func lastAppliedConfig(serverID string, current *models.ProxyConfig) *models.ProxyConfig {
+	var rev models.ConfigRevision
+	if err := database.DB.Where("server_id = ?", serverID).Order("revision DESC").First(&rev).Error; err != nil {
+		return nil
+	}
+	if current != nil && rev.Checksum == configChecksum(current) {
+		return nil
+	}
+	decodeRevisionFiles(&rev)
+	return revisionConfig(&rev)
+}
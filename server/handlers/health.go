@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/anveesa/proxera/database"
+	"github.com/anveesa/proxera/models"
+)
+
+// StartHealthChecker launches the background loop that probes every server
+// on the given interval and keeps Server.Status / LastChecked current.
+// At most `concurrency` probes run at once.
+func StartHealthChecker(interval time.Duration, concurrency int) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			runHealthSweep(interval, concurrency)
+			<-ticker.C
+		}
+	}()
+	log.Printf("Health checker started (interval=%s, concurrency=%d)", interval, concurrency)
+}
+
+func runHealthSweep(interval time.Duration, concurrency int) {
+	var servers []models.Server
+	if err := database.DB.Where("deleted_at IS NULL").Find(&servers).Error; err != nil {
+		log.Printf("Health checker: list servers: %v", err)
+		return
+	}
+
+	// Spread probes over the first tenth of the interval so a large fleet
+	// doesn't hit the network (and the SSH pool) in one burst.
+	maxJitter := interval / 10
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range servers {
+		server := &servers[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if maxJitter > 0 {
+				time.Sleep(time.Duration(rand.Int63n(int64(maxJitter))))
+			}
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			checkServerHealth(server)
+		}()
+	}
+	wg.Wait()
+}
+
+func checkServerHealth(server *models.Server) {
+	adapter, err := buildAdapter(server)
+	if err != nil {
+		log.Printf("Health checker: server %s: %v", server.ID, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	status, err := adapter.GetStatus(ctx)
+	if err != nil || status == "" {
+		status = string(models.StatusUnknown)
+	}
+	applyServerStatus(server, models.ServerStatus(status), time.Now())
+}
+
+// applyServerStatus persists the result of a health probe and notifies
+// WebSocket clients when the status actually changed.
+func applyServerStatus(server *models.Server, status models.ServerStatus, checkedAt time.Time) {
+	previous := server.Status
+	if err := database.DB.Model(&models.Server{}).Where("id = ?", server.ID).Updates(map[string]interface{}{
+		"status":       status,
+		"last_checked": checkedAt,
+	}).Error; err != nil {
+		log.Printf("Health checker: update server %s: %v", server.ID, err)
+		return
+	}
+	server.Status = status
+	server.LastChecked = &checkedAt
+
+	if previous != status {
+		Hub.BroadcastStatusChange(server.ID, string(status))
+	}
+}
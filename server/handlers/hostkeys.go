@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anveesa/proxera/database"
+	"github.com/anveesa/proxera/middleware"
+	"github.com/anveesa/proxera/models"
+	"github.com/anveesa/proxera/proxy"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm/clause"
+)
+
+// GetHostKey GET /api/v1/servers/:id/host-key?hop=target|jump
+func GetHostKey(c *gin.Context) {
+	server, ok := findServer(c)
+	if !ok {
+		return
+	}
+	id, ok := hostKeyID(c, server)
+	if !ok {
+		return
+	}
+	var key models.SSHHostKey
+	if err := database.DB.First(&key, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no host key pinned yet"})
+		return
+	}
+	c.JSON(http.StatusOK, key)
+}
+
+// ApproveHostKey POST /api/v1/servers/:id/host-key/approve?hop=target|jump
+// Trusts the pending (changed) host key in place of the pinned one.
+func ApproveHostKey(c *gin.Context) {
+	server, ok := findServer(c)
+	if !ok {
+		return
+	}
+	id, ok := hostKeyID(c, server)
+	if !ok {
+		return
+	}
+
+	var req models.ApproveHostKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var key models.SSHHostKey
+	if err := database.DB.First(&key, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no host key pinned yet"})
+		return
+	}
+	if key.PendingFingerprint == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "no changed host key awaiting approval"})
+		return
+	}
+	if key.PendingFingerprint != req.Fingerprint {
+		c.JSON(http.StatusConflict, gin.H{"error": "fingerprint does not match the pending host key"})
+		return
+	}
+
+	before := auditSnapshot(key)
+	now := time.Now()
+	key.KeyType = key.PendingKeyType
+	key.Fingerprint = key.PendingFingerprint
+	key.PublicKey = key.PendingPublicKey
+	key.ApprovedAt = &now
+	key.ApprovedBy = middleware.CurrentUser(c).Email
+	key.PendingKeyType = ""
+	key.PendingFingerprint = ""
+	key.PendingPublicKey = ""
+	key.PendingSeenAt = nil
+
+	if err := database.DB.Save(&key).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	proxyManager.GetSSHPool().Evict(server.ID)
+
+	recordAudit(c, "host_key_approve", "server", server.ID, before, key)
+	c.JSON(http.StatusOK, key)
+}
+
+// ResetHostKey DELETE /api/v1/servers/:id/host-key?hop=target|jump
+// Forgets the pinned key; the next connection pins whatever key it sees.
+func ResetHostKey(c *gin.Context) {
+	server, ok := findServer(c)
+	if !ok {
+		return
+	}
+	id, ok := hostKeyID(c, server)
+	if !ok {
+		return
+	}
+
+	var key models.SSHHostKey
+	if err := database.DB.First(&key, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no host key pinned yet"})
+		return
+	}
+	if err := database.DB.Delete(&key).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	proxyManager.GetSSHPool().Evict(server.ID)
+
+	recordAudit(c, "host_key_reset", "server", server.ID, key, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "host key reset"})
+}
+
+// hostKeyID resolves the ?hop= query (default target) to a store key.
+func hostKeyID(c *gin.Context, server *models.Server) (string, bool) {
+	hop := c.DefaultQuery("hop", proxy.HopTarget)
+	if hop != proxy.HopTarget && hop != proxy.HopJump {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "hop must be target or jump"})
+		return "", false
+	}
+	return proxy.HostKeyID(server.ID, hop), true
+}
+
+// ─── Host key store ───────────────────────────────────────────────────────────
+
+// hostKeyStore implements proxy.HostKeyStore on the ssh_host_keys table.
+type hostKeyStore struct{}
+
+var _ proxy.HostKeyStore = hostKeyStore{}
+
+func (hostKeyStore) PinnedKey(id string) (ssh.PublicKey, error) {
+	var rows []models.SSHHostKey
+	if err := database.DB.Where("id = ?", id).Limit(1).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(rows[0].PublicKey))
+	if err != nil {
+		return nil, fmt.Errorf("parse pinned key: %w", err)
+	}
+	return key, nil
+}
+
+func (s hostKeyStore) Pin(id, addr string, key ssh.PublicKey) error {
+	serverID, hop := proxy.ParseHostKeyID(id)
+	row := models.SSHHostKey{
+		ID:          id,
+		ServerID:    serverID,
+		Hop:         hop,
+		Address:     addr,
+		KeyType:     key.Type(),
+		Fingerprint: ssh.FingerprintSHA256(key),
+		PublicKey:   authorizedKey(key),
+	}
+	if err := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+		return fmt.Errorf("pin host key: %w", err)
+	}
+
+	// A concurrent first connection may have pinned a different key.
+	pinned, err := s.PinnedKey(id)
+	if err != nil {
+		return err
+	}
+	if pinned == nil || authorizedKey(pinned) != row.PublicKey {
+		s.Reject(id, addr, key)
+		return &proxy.HostKeyMismatchError{Addr: addr, Fingerprint: row.Fingerprint}
+	}
+	log.Printf("SSH: pinned %s host key %s for server %s (%s)", hop, row.Fingerprint, serverID, addr)
+	return nil
+}
+
+// Reject stores the unexpected key for review and raises a security alert
+// the first time each distinct key is seen.
+func (hostKeyStore) Reject(id, addr string, key ssh.PublicKey) {
+	fp := ssh.FingerprintSHA256(key)
+	serverID, hop := proxy.ParseHostKeyID(id)
+
+	var row models.SSHHostKey
+	if err := database.DB.First(&row, "id = ?", id).Error; err != nil {
+		log.Printf("SSH: host key mismatch for server %s: %v", serverID, err)
+		return
+	}
+	if row.PendingFingerprint == fp {
+		return
+	}
+
+	now := time.Now()
+	if err := database.DB.Model(&row).Updates(map[string]interface{}{
+		"pending_key_type":    key.Type(),
+		"pending_fingerprint": fp,
+		"pending_public_key":  authorizedKey(key),
+		"pending_seen_at":     now,
+	}).Error; err != nil {
+		log.Printf("SSH: record pending host key for server %s: %v", serverID, err)
+	}
+
+	var server models.Server
+	if err := database.DB.First(&server, "id = ?", serverID).Error; err != nil {
+		log.Printf("SSH: host key mismatch for unknown server %s", serverID)
+		return
+	}
+	title := "SSH host key changed on " + server.Name
+	if hop == proxy.HopJump {
+		title = "SSH host key changed on jump host of " + server.Name
+	}
+	raiseAlert(&server, models.SeverityCritical, models.CategorySecurity, title,
+		fmt.Sprintf("%s presented %s key %s, but %s is pinned. Connections are refused until the new key is approved.",
+			addr, key.Type(), fp, row.Fingerprint))
+}
+
+func authorizedKey(key ssh.PublicKey) string {
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+}
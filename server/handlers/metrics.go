@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/anveesa/proxera/database"
+	"github.com/anveesa/proxera/models"
+	"gorm.io/gorm"
+)
+
+const (
+	rawMetricsRetention    = 24 * time.Hour
+	minuteMetricsRetention = 7 * 24 * time.Hour
+	metricsPruneInterval   = time.Hour
+	metricsConcurrency     = 8
+)
+
+// StartMetricsCollector launches the background loop that samples
+// GetMetrics for every server on the given interval, stores the samples
+// with 1m / 1h rollups and drops hourly rollups older than retention.
+func StartMetricsCollector(interval, retention time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		lastPrune := time.Time{}
+		for {
+			runMetricsSweep(interval)
+			if time.Since(lastPrune) >= metricsPruneInterval {
+				pruneMetrics(retention)
+				lastPrune = time.Now()
+			}
+			<-ticker.C
+		}
+	}()
+	log.Printf("Metrics collector started (interval=%s, retention=%s)", interval, retention)
+}
+
+func runMetricsSweep(interval time.Duration) {
+	var servers []models.Server
+	if err := database.DB.Where("deleted_at IS NULL").Find(&servers).Error; err != nil {
+		log.Printf("Metrics collector: list servers: %v", err)
+		return
+	}
+
+	sem := make(chan struct{}, metricsConcurrency)
+	var wg sync.WaitGroup
+	for i := range servers {
+		server := &servers[i]
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			collectServerMetrics(server, interval)
+		}()
+	}
+	wg.Wait()
+}
+
+func collectServerMetrics(server *models.Server, interval time.Duration) {
+	adapter, err := buildAdapter(server)
+	if err != nil {
+		log.Printf("Metrics collector: server %s: %v", server.ID, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	m, err := adapter.GetMetrics(ctx)
+	if err != nil {
+		return
+	}
+	// A first sample's ratios cover the proxy's lifetime, not this
+	// interval; the next poll has something to diff against.
+	if m.Cumulative {
+		return
+	}
+	if err := recordMetrics(m, interval); err != nil {
+		log.Printf("Metrics collector: store sample for server %s: %v", server.ID, err)
+	}
+}
+
+// recordMetrics stores a raw sample and folds it into the 1m and 1h
+// rollup buckets it belongs to. period is the time the sample represents
+// and is used to turn RequestsPerSec into a request count.
+func recordMetrics(m *models.ServerMetrics, period time.Duration) error {
+	ts := m.Timestamp.UTC()
+	if ts.IsZero() {
+		ts = time.Now().UTC()
+	}
+	requests := m.RequestsPerSec * period.Seconds()
+
+	raw := models.MetricSample{
+		ServerID:          m.ServerID,
+		Resolution:        models.ResolutionRaw,
+		Timestamp:         ts,
+		Samples:           1,
+		Requests:          requests,
+		Errors:            requests * m.ErrorRate / 100,
+		RequestsPerSec:    m.RequestsPerSec,
+		ActiveConnections: float64(m.ActiveConnections),
+		ErrorRate:         m.ErrorRate,
+		P50Latency:        m.P50Latency,
+		P95Latency:        m.P95Latency,
+		P99Latency:        m.P99Latency,
+		CPUUsage:          m.CPUUsage,
+		MemUsage:          m.MemUsage,
+		NetworkIn:         m.NetworkIn,
+		NetworkOut:        m.NetworkOut,
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&raw).Error; err != nil {
+			return err
+		}
+		if err := foldRollup(tx, &raw, models.ResolutionMinute, ts.Truncate(time.Minute)); err != nil {
+			return err
+		}
+		return foldRollup(tx, &raw, models.ResolutionHour, ts.Truncate(time.Hour))
+	})
+}
+
+// foldRollup merges sample into the rollup row for (resolution, bucket):
+// counters are summed, gauges are kept as a running mean.
+func foldRollup(tx *gorm.DB, sample *models.MetricSample, res models.MetricResolution, bucket time.Time) error {
+	var row models.MetricSample
+	err := tx.Where("server_id = ? AND resolution = ? AND timestamp = ?", sample.ServerID, res, bucket).
+		Limit(1).Find(&row).Error
+	if err != nil {
+		return err
+	}
+	if row.ID == 0 {
+		row = *sample
+		row.ID = 0
+		row.Resolution = res
+		row.Timestamp = bucket
+		return tx.Create(&row).Error
+	}
+
+	n := float64(row.Samples)
+	mean := func(avg, v float64) float64 { return (avg*n + v) / (n + 1) }
+
+	row.Requests += sample.Requests
+	row.Errors += sample.Errors
+	row.RequestsPerSec = mean(row.RequestsPerSec, sample.RequestsPerSec)
+	row.ActiveConnections = mean(row.ActiveConnections, sample.ActiveConnections)
+	row.ErrorRate = mean(row.ErrorRate, sample.ErrorRate)
+	row.P50Latency = mean(row.P50Latency, sample.P50Latency)
+	row.P95Latency = mean(row.P95Latency, sample.P95Latency)
+	row.P99Latency = mean(row.P99Latency, sample.P99Latency)
+	row.CPUUsage = mean(row.CPUUsage, sample.CPUUsage)
+	row.MemUsage = mean(row.MemUsage, sample.MemUsage)
+	row.NetworkIn = mean(row.NetworkIn, sample.NetworkIn)
+	row.NetworkOut = mean(row.NetworkOut, sample.NetworkOut)
+	row.Samples++
+	return tx.Save(&row).Error
+}
+
+// pruneMetrics enforces the retention window for each resolution. Raw and
+// minute data is only kept long enough to serve recent views; hourly
+// rollups live for the configured retention.
+func pruneMetrics(retention time.Duration) {
+	now := time.Now().UTC()
+	cutoffs := map[models.MetricResolution]time.Time{
+		models.ResolutionRaw:    now.Add(-rawMetricsRetention),
+		models.ResolutionMinute: now.Add(-minuteMetricsRetention),
+		models.ResolutionHour:   now.Add(-retention),
+	}
+	for res, cutoff := range cutoffs {
+		if err := database.DB.Where("resolution = ? AND timestamp < ?", res, cutoff).
+			Delete(&models.MetricSample{}).Error; err != nil {
+			log.Printf("Metrics collector: prune %s samples: %v", res, err)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anveesa/proxera/crypto"
+	"github.com/anveesa/proxera/database"
+	"github.com/anveesa/proxera/diff"
+	"github.com/anveesa/proxera/middleware"
+	"github.com/anveesa/proxera/models"
+	"github.com/anveesa/proxera/proxy"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ListConfigRevisions GET /api/v1/servers/:id/config/revisions
+func ListConfigRevisions(c *gin.Context) {
+	server, ok := findServer(c)
+	if !ok {
+		return
+	}
+
+	var revisions []models.ConfigRevision
+	if err := database.DB.Omit("content", "files").
+		Where("server_id = ?", server.ID).
+		Order("revision DESC").
+		Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, revisions)
+}
+
+// GetConfigRevision GET /api/v1/servers/:id/config/revisions/:rev
+func GetConfigRevision(c *gin.Context) {
+	server, ok := findServer(c)
+	if !ok {
+		return
+	}
+	rev, ok := findRevision(c, server.ID, c.Param("rev"))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, rev)
+}
+
+// DiffConfigRevisions GET /api/v1/servers/:id/config/diff?from=N&to=M
+// "to" defaults to the latest revision.
+func DiffConfigRevisions(c *gin.Context) {
+	server, ok := findServer(c)
+	if !ok {
+		return
+	}
+
+	from, ok := findRevision(c, server.ID, c.Query("from"))
+	if !ok {
+		return
+	}
+	var to *models.ConfigRevision
+	if c.Query("to") != "" {
+		if to, ok = findRevision(c, server.ID, c.Query("to")); !ok {
+			return
+		}
+	} else {
+		to = &models.ConfigRevision{}
+		if err := database.DB.Where("server_id = ?", server.ID).Order("revision DESC").First(to).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "revision not found"})
+			return
+		}
+		decodeRevisionFiles(to)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from": from.Revision,
+		"to":   to.Revision,
+		"diff": diffConfigs(from, to),
+	})
+}
+
+// RollbackConfigRevision POST /api/v1/servers/:id/config/revisions/:rev/rollback
+func RollbackConfigRevision(c *gin.Context) {
+	server, ok := findServer(c)
+	if !ok {
+		return
+	}
+	target, ok := findRevision(c, server.ID, c.Param("rev"))
+	if !ok {
+		return
+	}
+
+	adapter, err := buildAdapter(server)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	previous := currentConfig(ctx, adapter)
+
+	result, err := applyConfig(ctx, adapter, revisionConfig(target), previous)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if !result.IsValid {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "revision failed validation", "validation": result})
+		return
+	}
+	revision := recordConfigChange(ctx, c, server, adapter, previous, revisionConfig(target), models.RevisionSourceRollback)
+
+	if err := adapter.Reload(ctx); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "reload: " + err.Error()})
+		return
+	}
+
+	recordAudit(c, "config_rollback", "server", server.ID, nil, map[string]interface{}{
+		"revision": target.Revision,
+		"config":   configFingerprint(revisionConfig(target)),
+	})
+	c.JSON(http.StatusOK, gin.H{"message": "rolled back", "revision": revision, "validation": result})
+}
+
+// ─── Helpers ──────────────────────────────────────────────────────────────────
+
+func findRevision(c *gin.Context, serverID, param string) (*models.ConfigRevision, bool) {
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "revision must be a number"})
+		return nil, false
+	}
+	var rev models.ConfigRevision
+	if err := database.DB.Where("server_id = ? AND revision = ?", serverID, n).First(&rev).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "revision not found"})
+		return nil, false
+	}
+	decodeRevisionFiles(&rev)
+	return &rev, true
+}
+
+func decodeRevisionFiles(rev *models.ConfigRevision) {
+	if rev.FilesJSON != "" {
+		json.Unmarshal([]byte(rev.FilesJSON), &rev.Files) //nolint:errcheck
+	}
+}
+
+// currentConfig returns the config on the proxy, or nil if it can't be
+// read. It is taken before a write so out-of-band edits are not lost from
+// history.
+func currentConfig(ctx context.Context, adapter proxy.ProxyAdapter) *models.ProxyConfig {
+	cfg, err := adapter.GetConfig(ctx)
+	if err != nil {
+		return nil
+	}
+	return cfg
+}
+
+// applyConfig writes cfg through the adapter: file by file when it has
+// files and the adapter supports that, else as a single document. Files in
+// current that cfg lacks are removed, so restoring a tree also undoes files
+// added since.
+func applyConfig(ctx context.Context, adapter proxy.ProxyAdapter, cfg, current *models.ProxyConfig) (*models.ConfigValidation, error) {
+	writer, ok := adapter.(proxy.ConfigFilesWriter)
+	if !ok || len(cfg.Files) == 0 {
+		return adapter.PutConfig(ctx, cfg.Content)
+	}
+
+	files := append([]models.ConfigFile(nil), cfg.Files...)
+	if current != nil {
+		keep := make(map[string]bool, len(cfg.Files))
+		for _, f := range cfg.Files {
+			keep[f.Path] = true
+		}
+		for _, f := range current.Files {
+			if !keep[f.Path] {
+				files = append(files, models.ConfigFile{Path: f.Path, Delete: true})
+			}
+		}
+	}
+	return writer.PutConfigFiles(ctx, files)
+}
+
+// recordConfigChange stores revisions for a successful PutConfig: previous
+// as an "observed" revision if it differs from the last one we know of, and
+// then the applied config as read back from the proxy (falling back to the
+// submitted content). Failures are logged; the write itself already happened.
+func recordConfigChange(ctx context.Context, c *gin.Context, server *models.Server, adapter proxy.ProxyAdapter, previous, submitted *models.ProxyConfig, source string) *models.ConfigRevision {
+	applied := submitted
+	if cfg := currentConfig(ctx, adapter); cfg != nil {
+		applied = cfg
+	}
+
+	author := ""
+	if user := middleware.CurrentUser(c); user != nil {
+		author = user.Email
+	}
+
+	var created *models.ConfigRevision
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var last models.ConfigRevision
+		if err := tx.Select("revision", "checksum").Where("server_id = ?", server.ID).
+			Order("revision DESC").Limit(1).Find(&last).Error; err != nil {
+			return err
+		}
+		next := last.Revision + 1
+
+		if previous != nil && configChecksum(previous) != last.Checksum {
+			if err := tx.Create(newRevision(server.ID, next, models.RevisionSourceObserved, "", previous)).Error; err != nil {
+				return err
+			}
+			next++
+		}
+
+		created = newRevision(server.ID, next, source, author, applied)
+		return tx.Create(created).Error
+	})
+	if err != nil {
+		log.Printf("Config revisions: record for %s: %v", server.Name, err)
+		return nil
+	}
+	created.Content = ""
+	created.Files = nil
+	return created
+}
+
+func newRevision(serverID string, n int, source, author string, cfg *models.ProxyConfig) *models.ConfigRevision {
+	rev := &models.ConfigRevision{
+		ID:       uuid.New().String(),
+		ServerID: serverID,
+		Revision: n,
+		Source:   source,
+		Author:   author,
+		Checksum: configChecksum(cfg),
+		Size:     configSize(cfg),
+		Content:  cfg.Content,
+	}
+	if len(cfg.Files) > 0 {
+		b, _ := json.Marshal(cfg.Files)
+		rev.FilesJSON = string(b)
+	}
+	return rev
+}
+
+// revisionConfig is the config a revision captured.
+func revisionConfig(rev *models.ConfigRevision) *models.ProxyConfig {
+	return &models.ProxyConfig{Content: rev.Content, Files: rev.Files}
+}
+
+// configChecksum identifies a config's content, covering every file of a
+// multi-file config.
+func configChecksum(cfg *models.ProxyConfig) string {
+	if len(cfg.Files) == 0 {
+		return crypto.HashToken(cfg.Content)
+	}
+	var sb strings.Builder
+	for _, f := range cfg.Files {
+		sb.WriteString(f.Path + "\x00" + f.Content + "\x00")
+	}
+	return crypto.HashToken(sb.String())
+}
+
+func configSize(cfg *models.ProxyConfig) int {
+	if len(cfg.Files) == 0 {
+		return len(cfg.Content)
+	}
+	n := 0
+	for _, f := range cfg.Files {
+		n += len(f.Content)
+	}
+	return n
+}
+
+// diffConfigs diffs two revisions, file by file for multi-file configs.
+func diffConfigs(from, to *models.ConfigRevision) string {
+	fromLabel := fmt.Sprintf("revision %d", from.Revision)
+	toLabel := fmt.Sprintf("revision %d", to.Revision)
+	if len(from.Files) == 0 && len(to.Files) == 0 {
+		return diff.Unified(fromLabel, toLabel, from.Content, to.Content, diff.DefaultContext)
+	}
+
+	before := make(map[string]string, len(from.Files))
+	var paths []string
+	for _, f := range from.Files {
+		before[f.Path] = f.Content
+		paths = append(paths, f.Path)
+	}
+	after := make(map[string]string, len(to.Files))
+	for _, f := range to.Files {
+		after[f.Path] = f.Content
+		if _, ok := before[f.Path]; !ok {
+			paths = append(paths, f.Path)
+		}
+	}
+
+	var sb strings.Builder
+	for _, p := range paths {
+		sb.WriteString(diff.Unified(p+" ("+fromLabel+")", p+" ("+toLabel+")", before[p], after[p], diff.DefaultContext))
+	}
+	return sb.String()
+}
@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"time"
 
 	"github.com/anveesa/proxera/database"
 	"github.com/anveesa/proxera/models"
+	"github.com/anveesa/proxera/proxy"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -40,6 +42,10 @@ func CreateRoute(c *gin.Context) {
 		return
 	}
 
+	if !canManageRouteServer(c, req.ServerID) {
+		return
+	}
+
 	if req.LoadBalancingMethod == "" {
 		req.LoadBalancingMethod = models.LBRoundRobin
 	}
@@ -65,12 +71,16 @@ func CreateRoute(c *gin.Context) {
 		MiddlewaresJSON:     middlewaresJSON,
 		Priority:            req.Priority,
 	}
+	if !validateRoute(c, &route) {
+		return
+	}
 
 	if err := database.DB.Create(&route).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	recordAudit(c, "create", "route", route.ID, nil, toRouteResponse(route))
 	c.JSON(http.StatusCreated, toRouteResponse(route))
 }
 
@@ -95,6 +105,7 @@ func UpdateRoute(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	before := auditSnapshot(toRouteResponse(*route))
 
 	route.Name = req.Name
 	route.Enabled = req.Enabled
@@ -111,12 +122,16 @@ func UpdateRoute(c *gin.Context) {
 		b, _ := json.Marshal(req.Middlewares)
 		route.MiddlewaresJSON = string(b)
 	}
+	if !validateRoute(c, route) {
+		return
+	}
 
 	if err := database.DB.Save(route).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	recordAudit(c, "update", "route", route.ID, before, toRouteResponse(*route))
 	c.JSON(http.StatusOK, toRouteResponse(*route))
 }
 
@@ -132,6 +147,7 @@ func PatchRoute(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	before := auditSnapshot(toRouteResponse(*route))
 
 	if req.Name != nil {
 		route.Name = *req.Name
@@ -167,12 +183,16 @@ func PatchRoute(c *gin.Context) {
 		b, _ := json.Marshal(req.Middlewares)
 		route.MiddlewaresJSON = string(b)
 	}
+	if !validateRoute(c, route) {
+		return
+	}
 
 	if err := database.DB.Save(route).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	recordAudit(c, "patch", "route", route.ID, before, toRouteResponse(*route))
 	c.JSON(http.StatusOK, toRouteResponse(*route))
 }
 
@@ -190,6 +210,7 @@ func DeleteRoute(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, "delete", "route", route.ID, toRouteResponse(*route), nil)
 	c.JSON(http.StatusOK, gin.H{"message": "route deleted"})
 }
 
@@ -201,16 +222,96 @@ func ToggleRoute(c *gin.Context) {
 	}
 
 	route.Enabled = !route.Enabled
+	if route.Enabled && !validateRoute(c, route) {
+		return
+	}
 	if err := database.DB.Save(route).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	recordAudit(c, "toggle", "route", route.ID,
+		map[string]interface{}{"enabled": !route.Enabled},
+		map[string]interface{}{"enabled": route.Enabled})
 	c.JSON(http.StatusOK, gin.H{"id": route.ID, "enabled": route.Enabled})
 }
 
+// SyncServerRoutes POST /api/v1/servers/:id/routes/sync
+// Renders the server's enabled routes into proxy configuration, applies it
+// via PutConfig and reloads. With ?dryRun=true the rendered config is
+// returned without touching the proxy.
+func SyncServerRoutes(c *gin.Context) {
+	server, ok := findServer(c)
+	if !ok {
+		return
+	}
+
+	var routes []models.Route
+	if err := database.DB.Where("server_id = ? AND deleted_at IS NULL", server.ID).Find(&routes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	adapter, err := buildAdapter(server)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	// Text configs are merged into what is on the box so unmanaged
+	// directives survive; Traefik's dynamic file is owned entirely by us,
+	// so its current content only matters for the revision history.
+	current := ""
+	previous, err := adapter.GetConfig(ctx)
+	if server.ProxyType != models.ProxyTraefik {
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "fetch current config: " + err.Error()})
+			return
+		}
+		current = previous.Content
+	}
+
+	content, err := proxy.RenderRoutes(string(server.ProxyType), routes, current)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("dryRun") == "true" {
+		c.JSON(http.StatusOK, gin.H{"content": content})
+		return
+	}
+
+	result, err := adapter.PutConfig(ctx, content)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if !result.IsValid {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "rendered config failed validation", "validation": result, "content": content})
+		return
+	}
+	recordConfigChange(ctx, c, server, adapter, previous, &models.ProxyConfig{Content: content}, models.RevisionSourceSync)
+
+	if err := adapter.Reload(ctx); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "reload: " + err.Error()})
+		return
+	}
+
+	recordAudit(c, "routes_sync", "server", server.ID, nil, map[string]interface{}{
+		"routes": len(routes),
+		"config": configFingerprint(&models.ProxyConfig{Content: content}),
+	})
+	c.JSON(http.StatusOK, gin.H{"message": "routes synced", "routes": len(routes), "validation": result})
+}
+
 // ─── Helpers ──────────────────────────────────────────────────────────────────
 
+// findRoute loads the :id route. For mutating requests it also enforces
+// the caller's operator tag scope on the route's server.
 func findRoute(c *gin.Context) (*models.Route, bool) {
 	id := c.Param("id")
 	var route models.Route
@@ -218,9 +319,53 @@ func findRoute(c *gin.Context) (*models.Route, bool) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "route not found"})
 		return nil, false
 	}
+	if c.Request.Method != http.MethodGet && !canManageRouteServer(c, route.ServerID) {
+		return nil, false
+	}
 	return &route, true
 }
 
+// canManageRouteServer applies canManageServer to the server a route
+// belongs to.
+func canManageRouteServer(c *gin.Context, serverID string) bool {
+	var server models.Server
+	if err := database.DB.Where("id = ? AND deleted_at IS NULL", serverID).First(&server).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "server not found"})
+		return false
+	}
+	unmarshalTags(&server)
+	return canManageServer(c, server.Tags)
+}
+
+// validateRoute normalizes a route's match fields, then rejects it if they
+// could not be written into a proxy config safely (400), or if, enabled, it
+// would match the same requests as another enabled route of its server
+// (409).
+func validateRoute(c *gin.Context, route *models.Route) bool {
+	proxy.NormalizeRoute(route)
+	if err := proxy.ValidateRoute(*route); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if !route.Enabled {
+		return true
+	}
+	var others []models.Route
+	if err := database.DB.Where("server_id = ? AND id <> ? AND enabled = ? AND deleted_at IS NULL", route.ServerID, route.ID, true).
+		Find(&others).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	for i := range others {
+		proxy.NormalizeRoute(&others[i])
+	}
+	if err := proxy.DuplicateRoutes(append(others, *route)); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
 func toRouteResponse(r models.Route) models.RouteResponse {
 	var middlewares []string
 	if r.MiddlewaresJSON != "" {
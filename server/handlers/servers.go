@@ -4,20 +4,34 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"path"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/anveesa/proxera/auth"
+	"github.com/anveesa/proxera/config"
 	"github.com/anveesa/proxera/crypto"
 	"github.com/anveesa/proxera/database"
+	"github.com/anveesa/proxera/middleware"
 	"github.com/anveesa/proxera/models"
 	"github.com/anveesa/proxera/proxy"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
-var proxyManager = proxy.NewManager()
+var proxyManager = newProxyManager()
+
+func newProxyManager() *proxy.Manager {
+	m := proxy.NewManager()
+	m.GetSSHPool().SetHostKeyStore(hostKeyStore{})
+	return m
+}
 
 // ListServers GET /api/v1/servers
 func ListServers(c *gin.Context) {
@@ -70,9 +84,20 @@ func CreateServer(c *gin.Context) {
 		return
 	}
 
+	if !canManageServer(c, req.Tags) {
+		return
+	}
+
 	if req.Port == 0 {
 		req.Port = defaultPort(string(req.ProxyType))
 	}
+	if err := validateProxySettings(req.NGINX, req.Traefik, req.HAProxy, req.Logs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !checkHostSettings(c, &models.Server{}, req.NGINX, req.Traefik, req.HAProxy, req.Logs) {
+		return
+	}
 
 	server := models.Server{
 		ID:             uuid.New().String(),
@@ -85,8 +110,15 @@ func CreateServer(c *gin.Context) {
 		Location:       req.Location,
 		Description:    req.Description,
 		SSHUser:        req.SSHUser,
+		JumpHost:       req.JumpHost,
+		JumpPort:       req.JumpPort,
+		JumpUser:       req.JumpUser,
 		APIURL:         req.APIURL,
 	}
+	if err := applyProxySettings(&server, req.NGINX, req.Traefik, req.HAProxy, req.Logs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "encryption failed"})
+		return
+	}
 
 	if req.Tags != nil {
 		b, _ := json.Marshal(req.Tags)
@@ -113,12 +145,23 @@ func CreateServer(c *gin.Context) {
 		server.APITokenEnc = enc
 	}
 
+	if err := encryptSecrets(
+		secretInput{&server.SSHPassphraseEnc, req.SSHPassphrase},
+		secretInput{&server.SSHPasswordEnc, req.SSHPassword},
+		secretInput{&server.JumpKeyEnc, req.JumpKey},
+		secretInput{&server.JumpPassphraseEnc, req.JumpPassphrase},
+	); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "encryption failed"})
+		return
+	}
+
 	if err := database.DB.Create(&server).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	unmarshalTags(&server)
+	recordAudit(c, "create", "server", server.ID, nil, serverAuditSnapshot(&server))
 	c.JSON(http.StatusCreated, server)
 }
 
@@ -134,6 +177,17 @@ func UpdateServer(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.Tags != nil && !canManageServer(c, req.Tags) {
+		return
+	}
+	if err := validateProxySettings(req.NGINX, req.Traefik, req.HAProxy, req.Logs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !checkHostSettings(c, server, req.NGINX, req.Traefik, req.HAProxy, req.Logs) {
+		return
+	}
+	before := serverAuditSnapshot(server)
 
 	server.Name = req.Name
 	server.Host = req.Host
@@ -145,7 +199,14 @@ func UpdateServer(c *gin.Context) {
 	server.Location = req.Location
 	server.Description = req.Description
 	server.SSHUser = req.SSHUser
+	server.JumpHost = req.JumpHost
+	server.JumpPort = req.JumpPort
+	server.JumpUser = req.JumpUser
 	server.APIURL = req.APIURL
+	if err := applyProxySettings(server, req.NGINX, req.Traefik, req.HAProxy, req.Logs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "encryption failed"})
+		return
+	}
 
 	if req.Tags != nil {
 		b, _ := json.Marshal(req.Tags)
@@ -159,6 +220,15 @@ func UpdateServer(c *gin.Context) {
 		enc, _ := crypto.Encrypt(req.APIToken)
 		server.APITokenEnc = enc
 	}
+	if err := encryptSecrets(
+		secretInput{&server.SSHPassphraseEnc, req.SSHPassphrase},
+		secretInput{&server.SSHPasswordEnc, req.SSHPassword},
+		secretInput{&server.JumpKeyEnc, req.JumpKey},
+		secretInput{&server.JumpPassphraseEnc, req.JumpPassphrase},
+	); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "encryption failed"})
+		return
+	}
 
 	proxyManager.GetSSHPool().Evict(server.ID)
 
@@ -168,6 +238,7 @@ func UpdateServer(c *gin.Context) {
 	}
 
 	unmarshalTags(server)
+	recordAudit(c, "update", "server", server.ID, before, serverAuditSnapshot(server))
 	c.JSON(http.StatusOK, server)
 }
 
@@ -183,6 +254,17 @@ func PatchServer(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.Tags != nil && !canManageServer(c, req.Tags) {
+		return
+	}
+	if err := validateProxySettings(req.NGINX, req.Traefik, req.HAProxy, req.Logs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !checkHostSettings(c, server, req.NGINX, req.Traefik, req.HAProxy, req.Logs) {
+		return
+	}
+	before := serverAuditSnapshot(server)
 
 	if req.Name != nil {
 		server.Name = *req.Name
@@ -208,9 +290,22 @@ func PatchServer(c *gin.Context) {
 	if req.SSHUser != nil {
 		server.SSHUser = *req.SSHUser
 	}
+	if req.JumpHost != nil {
+		server.JumpHost = *req.JumpHost
+	}
+	if req.JumpPort != nil {
+		server.JumpPort = *req.JumpPort
+	}
+	if req.JumpUser != nil {
+		server.JumpUser = *req.JumpUser
+	}
 	if req.APIURL != nil {
 		server.APIURL = *req.APIURL
 	}
+	if err := applyProxySettings(server, req.NGINX, req.Traefik, req.HAProxy, req.Logs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "encryption failed"})
+		return
+	}
 	if req.Tags != nil {
 		b, _ := json.Marshal(req.Tags)
 		server.TagsJSON = string(b)
@@ -223,6 +318,15 @@ func PatchServer(c *gin.Context) {
 		enc, _ := crypto.Encrypt(*req.APIToken)
 		server.APITokenEnc = enc
 	}
+	if err := encryptSecrets(
+		secretInput{&server.SSHPassphraseEnc, deref(req.SSHPassphrase)},
+		secretInput{&server.SSHPasswordEnc, deref(req.SSHPassword)},
+		secretInput{&server.JumpKeyEnc, deref(req.JumpKey)},
+		secretInput{&server.JumpPassphraseEnc, deref(req.JumpPassphrase)},
+	); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "encryption failed"})
+		return
+	}
 
 	proxyManager.GetSSHPool().Evict(server.ID)
 
@@ -232,6 +336,7 @@ func PatchServer(c *gin.Context) {
 	}
 
 	unmarshalTags(server)
+	recordAudit(c, "patch", "server", server.ID, before, serverAuditSnapshot(server))
 	c.JSON(http.StatusOK, server)
 }
 
@@ -243,6 +348,7 @@ func DeleteServer(c *gin.Context) {
 	}
 
 	proxyManager.GetSSHPool().Evict(server.ID)
+	proxy.ForgetCounters(server.ID)
 
 	now := time.Now()
 	server.DeletedAt = &now
@@ -251,6 +357,7 @@ func DeleteServer(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, "delete", "server", server.ID, serverAuditSnapshot(server), nil)
 	c.JSON(http.StatusOK, gin.H{"message": "server deleted"})
 }
 
@@ -271,16 +378,13 @@ func ServerHealth(c *gin.Context) {
 	defer cancel()
 
 	latency, pingErr := adapter.Ping(ctx)
-	status := "online"
+	status := models.StatusOnline
 	if pingErr != nil {
-		status = "offline"
+		status = models.StatusOffline
 	}
 
 	now := time.Now()
-	database.DB.Model(server).Updates(map[string]interface{}{
-		"status":       status,
-		"last_checked": now,
-	})
+	applyServerStatus(server, status, now)
 
 	c.JSON(http.StatusOK, gin.H{
 		"serverId":  server.ID,
@@ -348,12 +452,28 @@ func PutServerConfig(c *gin.Context) {
 	}
 
 	var body struct {
-		Content string `json:"content" binding:"required"`
+		Content string `json:"content"`
+		// Files replaces individual files of a multi-file config instead
+		// of Content; other files are left alone.
+		Files []models.ConfigFile `json:"files" binding:"dive"`
+		// Guard, if set, also reloads the proxy and restores the previous
+		// config if it turns unhealthy.
+		Guard *models.GuardOptions `json:"guard"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if body.Content == "" && len(body.Files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "content or files is required"})
+		return
+	}
+	if body.Guard != nil {
+		if err := validateGuard(body.Guard); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
 	adapter, err := buildAdapter(server)
 	if err != nil {
@@ -361,18 +481,155 @@ func PutServerConfig(c *gin.Context) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	submitted := &models.ProxyConfig{Content: body.Content, Files: body.Files}
+	if len(body.Files) > 0 {
+		if _, ok := adapter.(proxy.ConfigFilesWriter); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "this proxy type does not support multi-file config"})
+			return
+		}
+	}
+
+	ctx, cancel := guardContext(c, body.Guard)
+	defer cancel()
+
+	previous := currentConfig(ctx, adapter)
+
+	result, err := applyConfig(ctx, adapter, submitted, nil)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if !result.IsValid {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	recordConfigChange(ctx, c, server, adapter, previous, submitted, models.RevisionSourcePut)
+	recordAudit(c, "config_put", "server", server.ID, nil, map[string]interface{}{
+		"config": configFingerprint(submitted),
+	})
+
+	if body.Guard == nil {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+	guard := guardedReload(ctx, c, server, adapter, previous, body.Guard)
+	status := http.StatusOK
+	if !guard.Healthy {
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, gin.H{"isValid": result.IsValid, "errors": result.Errors, "guard": guard})
+}
+
+// ApplyServerConfigChanges POST /api/v1/servers/:id/config/changes
+// Applies object-level edits (frontends, backends, servers) in a single
+// transaction on proxies with a configuration API that supports it.
+func ApplyServerConfigChanges(c *gin.Context) {
+	server, ok := findServer(c)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Changes []models.ConfigChange `json:"changes" binding:"required,min=1,dive"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adapter, err := buildAdapter(server)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	writer, ok := adapter.(proxy.ConfigChangesWriter)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "this proxy type does not support object-level config changes"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
 	defer cancel()
 
-	result, err := adapter.PutConfig(ctx, body.Content)
+	previous := currentConfig(ctx, adapter)
+
+	result, err := writer.ApplyConfigChanges(ctx, body.Changes)
 	if err != nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
 		return
 	}
+	if !result.IsValid {
+		c.JSON(http.StatusOK, result)
+		return
+	}
 
+	// There is no submitted document to fall back on; only record the
+	// result if it can be read back.
+	if applied := currentConfig(ctx, adapter); applied != nil {
+		recordConfigChange(ctx, c, server, adapter, previous, applied, models.RevisionSourceChanges)
+	}
+	summary := make([]string, len(body.Changes))
+	for i, ch := range body.Changes {
+		summary[i] = ch.Op + " " + ch.Kind + " " + ch.Name
+	}
+	recordAudit(c, "config_changes", "server", server.ID, nil, map[string]interface{}{"changes": summary})
 	c.JSON(http.StatusOK, result)
 }
 
+// SetBackendServerState POST /api/v1/servers/:id/backends/:backend/servers/:name/state
+func SetBackendServerState(c *gin.Context) {
+	server, ok := findServer(c)
+	if !ok {
+		return
+	}
+
+	var change models.ServerStateChange
+	if err := c.ShouldBindJSON(&change); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if change.AdminState == "" && change.Weight == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "adminState or weight is required"})
+		return
+	}
+
+	adapter, err := buildAdapter(server)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	controller, ok := adapter.(proxy.ServerStateController)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "this proxy type does not support runtime server state changes"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	backend, name := c.Param("backend"), c.Param("name")
+	state, err := controller.SetServerState(ctx, backend, name, change)
+	var unsupported *proxy.ErrNotSupported
+	if errors.As(err, &unsupported) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, proxy.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("server %q not found in backend %q", name, backend)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "server_state", "server", server.ID, nil, map[string]interface{}{
+		"backend": backend, "server": name, "adminState": change.AdminState, "weight": change.Weight,
+	})
+	c.JSON(http.StatusOK, state)
+}
+
 // ReloadServer POST /api/v1/servers/:id/reload
 func ReloadServer(c *gin.Context) {
 	server, ok := findServer(c)
@@ -380,12 +637,42 @@ func ReloadServer(c *gin.Context) {
 		return
 	}
 
+	// The body is optional; {"guard": {...}} makes this a guarded reload.
+	var body struct {
+		Guard *models.GuardOptions `json:"guard"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if body.Guard != nil {
+		if err := validateGuard(body.Guard); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	adapter, err := buildAdapter(server)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	if body.Guard != nil {
+		ctx, cancel := guardContext(c, body.Guard)
+		defer cancel()
+
+		snapshot := lastAppliedConfig(server.ID, currentConfig(ctx, adapter))
+		guard := guardedReload(ctx, c, server, adapter, snapshot, body.Guard)
+		recordAudit(c, "reload", "server", server.ID, nil, map[string]interface{}{"guarded": true, "healthy": guard.Healthy})
+		status := http.StatusOK
+		if !guard.Healthy {
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, gin.H{"message": "reload completed", "guard": guard})
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
 	defer cancel()
 
@@ -394,9 +681,19 @@ func ReloadServer(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, "reload", "server", server.ID, nil, nil)
 	c.JSON(http.StatusOK, gin.H{"message": "reload initiated"})
 }
 
+// logEvent is one parsed log line as sent by StreamServerLogs.
+type logEvent struct {
+	ID         string           `json:"id"`
+	ServerID   string           `json:"serverId"`
+	ServerName string           `json:"serverName"`
+	ProxyType  models.ProxyType `json:"proxyType"`
+	*models.LogEntry
+}
+
 // StreamServerLogs GET /api/v1/servers/:id/logs (SSE)
 func StreamServerLogs(c *gin.Context) {
 	server, ok := findServer(c)
@@ -430,16 +727,18 @@ func StreamServerLogs(c *gin.Context) {
 	scanner := bufio.NewScanner(rc)
 	logID := 0
 	for scanner.Scan() {
-		line := scanner.Text()
+		entry := proxy.ParseLog(server.ProxyType, scanner.Text())
+		if entry.Timestamp.IsZero() {
+			entry.Timestamp = time.Now()
+		}
 		logID++
-		entry := map[string]interface{}{
-			"id":        fmt.Sprintf("l%d", logID),
-			"serverId":  server.ID,
-			"level":     parseLogLevel(line),
-			"message":   line,
-			"timestamp": time.Now().Format(time.RFC3339),
-		}
-		data, _ := json.Marshal(entry)
+		data, _ := json.Marshal(logEvent{
+			ID:         fmt.Sprintf("l%d", logID),
+			ServerID:   server.ID,
+			ServerName: server.Name,
+			ProxyType:  server.ProxyType,
+			LogEntry:   entry,
+		})
 		fmt.Fprintf(c.Writer, "id: l%d\nevent: log\ndata: %s\n\n", logID, string(data))
 		c.Writer.Flush()
 
@@ -453,6 +752,8 @@ func StreamServerLogs(c *gin.Context) {
 
 // ─── Helpers ──────────────────────────────────────────────────────────────────
 
+// findServer loads the :id server. For mutating requests it also enforces
+// the caller's operator tag scope.
 func findServer(c *gin.Context) (*models.Server, bool) {
 	id := c.Param("id")
 	var server models.Server
@@ -460,9 +761,250 @@ func findServer(c *gin.Context) (*models.Server, bool) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "server not found"})
 		return nil, false
 	}
+	if c.Request.Method != http.MethodGet {
+		unmarshalTags(&server)
+		if !canManageServer(c, server.Tags) {
+			return nil, false
+		}
+	}
 	return &server, true
 }
 
+// canManageServer checks that the caller may manage a server carrying tags,
+// writing a 403 if not.
+func canManageServer(c *gin.Context, tags []string) bool {
+	if auth.CanManageServer(middleware.CurrentUser(c), tags) {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "forbidden: server is outside your tag scope"})
+	return false
+}
+
+// serverAuditSnapshot is the server's API view plus its encrypted secrets,
+// so credential changes show up (redacted) in audit diffs.
+func serverAuditSnapshot(s *models.Server) map[string]interface{} {
+	m := auditSnapshot(s)
+	m["sshKey"] = s.SSHKeyContent
+	m["sshPassphrase"] = s.SSHPassphraseEnc
+	m["sshPassword"] = s.SSHPasswordEnc
+	m["jumpKey"] = s.JumpKeyEnc
+	m["jumpPassphrase"] = s.JumpPassphraseEnc
+	m["apiToken"] = s.APITokenEnc
+	m["dataPlanePassword"] = s.HAProxy.PasswordEnc
+	return m
+}
+
+// configFingerprint summarises a config for the audit log without storing
+// the config itself.
+func configFingerprint(cfg *models.ProxyConfig) string {
+	if len(cfg.Files) > 0 {
+		return fmt.Sprintf("sha256:%s (%d files, %d bytes)", configChecksum(cfg), len(cfg.Files), configSize(cfg))
+	}
+	return fmt.Sprintf("sha256:%s (%d bytes)", configChecksum(cfg), configSize(cfg))
+}
+
+// validateProxySettings checks whichever per-proxy settings a request
+// carries. NGINX commands are taken as given; who may set them is up to
+// checkHostSettings.
+func validateProxySettings(n *models.NGINXSettings, t *models.TraefikSettings, h *models.HAProxySettingsRequest, l *models.LogSettings) error {
+	if n != nil {
+		for name, p := range map[string]string{
+			"configPath":    n.ConfigPath,
+			"accessLogPath": n.AccessLogPath,
+			"errorLogPath":  n.ErrorLogPath,
+		} {
+			if p != "" && !path.IsAbs(p) {
+				return fmt.Errorf("nginx.%s must be an absolute path", name)
+			}
+		}
+		if n.StatusURL != "" {
+			u, err := url.Parse(n.StatusURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("nginx.statusUrl must be an http(s) URL")
+			}
+		}
+	}
+	if t != nil {
+		if t.FileDir != "" && !path.IsAbs(t.FileDir) {
+			return fmt.Errorf("traefik.fileDir must be an absolute path")
+		}
+		if t.FileName != "" {
+			ext := path.Ext(t.FileName)
+			if strings.Contains(t.FileName, "/") || (ext != ".yml" && ext != ".yaml" && ext != ".toml") {
+				return fmt.Errorf("traefik.fileName must be a .yml, .yaml or .toml file name")
+			}
+		}
+		if t.FileAccess != "" && t.FileAccess != models.FileAccessSSH && t.FileAccess != models.FileAccessLocal {
+			return fmt.Errorf("traefik.fileAccess must be ssh or local")
+		}
+		// A local directory is on Proxera's own host, so it is confined to
+		// the root the administrator set.
+		if t.FileAccess == models.FileAccessLocal && t.FileDir != "" {
+			if config.C.TraefikLocalRoot == "" {
+				return fmt.Errorf("traefik.fileAccess local is disabled (TRAEFIK_LOCAL_ROOT is not set)")
+			}
+			if !proxy.InsideDir(config.C.TraefikLocalRoot, t.FileDir) {
+				return fmt.Errorf("traefik.fileDir must be under %s", config.C.TraefikLocalRoot)
+			}
+		}
+		if t.MetricsURL != "" {
+			u, err := url.Parse(t.MetricsURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("traefik.metricsUrl must be an http(s) URL")
+			}
+		}
+	}
+	if h != nil {
+		if h.DataPlaneURL != "" {
+			u, err := url.Parse(h.DataPlaneURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("haproxy.dataPlaneUrl must be an http(s) URL")
+			}
+		}
+		if h.APIVersion != "" && h.APIVersion != "v2" && h.APIVersion != "v3" {
+			return fmt.Errorf("haproxy.apiVersion must be v2 or v3")
+		}
+		if h.StatsSocket != "" && (!path.IsAbs(h.StatsSocket) || strings.ContainsAny(h.StatsSocket, ", \t\n")) {
+			return fmt.Errorf("haproxy.statsSocket must be an absolute path")
+		}
+	}
+	if l != nil {
+		for name, p := range map[string]string{
+			"accessLogPath": l.AccessLogPath,
+			"errorLogPath":  l.ErrorLogPath,
+		} {
+			if p != "" && !path.IsAbs(p) {
+				return fmt.Errorf("logs.%s must be an absolute path", name)
+			}
+		}
+		switch l.Source {
+		case "":
+		case models.LogSourceFile:
+			if l.AccessLogPath == "" && l.ErrorLogPath == "" {
+				return fmt.Errorf("logs.accessLogPath or logs.errorLogPath is required for the file source")
+			}
+		case models.LogSourceJournal:
+			if l.Unit == "" {
+				return fmt.Errorf("logs.unit is required for the journal source")
+			}
+		case models.LogSourceDocker:
+			if l.Container == "" {
+				return fmt.Errorf("logs.container is required for the docker source")
+			}
+		default:
+			return fmt.Errorf("logs.source must be file, journal or docker")
+		}
+	}
+	return nil
+}
+
+// checkHostSettings writes a 403 and returns false if the request changes
+// a setting that decides what runs as root on the host, or which files are
+// read there, and the caller lacks servers:host. These run with the SSH
+// credentials stored on s, which the caller need never have seen.
+func checkHostSettings(c *gin.Context, s *models.Server, n *models.NGINXSettings, t *models.TraefikSettings, h *models.HAProxySettingsRequest, l *models.LogSettings) bool {
+	field := changedHostSetting(s, n, t, h, l)
+	if field == "" || middleware.HasPermission(c, auth.PermServersHost) {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "forbidden: changing " + field + " requires " + string(auth.PermServersHost)})
+	return false
+}
+
+// changedHostSetting names the first host setting the request changes.
+// Log files outside /var/log count as one, as even without sudo the SSH
+// user can read its own keys. So does the status URL, which is fetched
+// from the host with sudo, and Traefik's file settings, as Proxera reads,
+// writes and deletes config files in that directory, and HAProxy's stats
+// socket, which is written to over SSH.
+func changedHostSetting(s *models.Server, n *models.NGINXSettings, t *models.TraefikSettings, h *models.HAProxySettingsRequest, l *models.LogSettings) string {
+	if n != nil {
+		if sudoOn(n.UseSudo) && !sudoOn(s.NGINX.UseSudo) {
+			return "nginx.useSudo"
+		}
+		for _, f := range []struct {
+			name     string
+			was, now string
+		}{
+			{"nginx.binary", s.NGINX.Binary, n.Binary},
+			{"nginx.configPath", s.NGINX.ConfigPath, n.ConfigPath},
+			{"nginx.accessLogPath", s.NGINX.AccessLogPath, n.AccessLogPath},
+			{"nginx.errorLogPath", s.NGINX.ErrorLogPath, n.ErrorLogPath},
+			{"nginx.testCommand", s.NGINX.TestCommand, n.TestCommand},
+			{"nginx.reloadCommand", s.NGINX.ReloadCommand, n.ReloadCommand},
+			{"nginx.dockerContainer", s.NGINX.DockerContainer, n.DockerContainer},
+			{"nginx.statusUrl", s.NGINX.StatusURL, n.StatusURL},
+		} {
+			if f.was != f.now {
+				return f.name
+			}
+		}
+	}
+	if t != nil {
+		if sudoOn(t.UseSudo) && !sudoOn(s.Traefik.UseSudo) {
+			return "traefik.useSudo"
+		}
+		for _, f := range []struct {
+			name     string
+			was, now string
+		}{
+			{"traefik.fileDir", s.Traefik.FileDir, t.FileDir},
+			{"traefik.fileAccess", s.Traefik.FileAccess, t.FileAccess},
+			{"traefik.sshPort", strconv.Itoa(s.Traefik.SSHPort), strconv.Itoa(t.SSHPort)},
+		} {
+			if f.was != f.now {
+				return f.name
+			}
+		}
+	}
+	if h != nil && h.StatsSocket != s.HAProxy.StatsSocket {
+		return "haproxy.statsSocket"
+	}
+	if l != nil {
+		if l.UseSudo != nil && *l.UseSudo && (s.Logs.UseSudo == nil || !*s.Logs.UseSudo) {
+			return "logs.useSudo"
+		}
+		for _, f := range []struct {
+			name     string
+			was, now string
+		}{
+			{"logs.accessLogPath", s.Logs.AccessLogPath, l.AccessLogPath},
+			{"logs.errorLogPath", s.Logs.ErrorLogPath, l.ErrorLogPath},
+		} {
+			if f.now != f.was && f.now != "" && !strings.HasPrefix(path.Clean(f.now), "/var/log/") {
+				return f.name
+			}
+		}
+	}
+	return ""
+}
+
+// sudoOn reports whether a UseSudo setting, where unset means true, is on.
+func sudoOn(useSudo *bool) bool {
+	return useSudo == nil || *useSudo
+}
+
+// applyProxySettings replaces the settings a request carries. The stored
+// Data Plane API password is kept unless a new one is given.
+func applyProxySettings(s *models.Server, n *models.NGINXSettings, t *models.TraefikSettings, h *models.HAProxySettingsRequest, l *models.LogSettings) error {
+	if n != nil {
+		s.NGINX = *n
+	}
+	if t != nil {
+		s.Traefik = *t
+	}
+	if l != nil {
+		s.Logs = *l
+	}
+	if h != nil {
+		enc := s.HAProxy.PasswordEnc
+		s.HAProxy = h.HAProxySettings
+		s.HAProxy.PasswordEnc = enc
+		return encryptSecrets(secretInput{&s.HAProxy.PasswordEnc, h.Password})
+	}
+	return nil
+}
+
 func unmarshalTags(s *models.Server) {
 	if s.TagsJSON != "" {
 		json.Unmarshal([]byte(s.TagsJSON), &s.Tags) //nolint:errcheck
@@ -473,28 +1015,92 @@ func unmarshalTags(s *models.Server) {
 }
 
 func buildAdapter(s *models.Server) (proxy.ProxyAdapter, error) {
-	var sshKey, apiToken string
-	if s.SSHKeyContent != "" {
-		dec, err := crypto.Decrypt(s.SSHKeyContent)
-		if err != nil {
-			return nil, fmt.Errorf("decrypt ssh key: %w", err)
+	sshCfg := proxy.SSHConfig{SSHAuth: proxy.SSHAuth{User: s.SSHUser}}
+	settings := proxy.AdapterSettings{
+		NGINX:            s.NGINX,
+		Traefik:          s.Traefik,
+		HAProxy:          s.HAProxy,
+		Logs:             s.Logs,
+		TraefikLocalRoot: config.C.TraefikLocalRoot,
+	}
+	var apiToken string
+	secrets := []secretField{
+		{s.SSHKeyContent, &sshCfg.PrivateKey, "ssh key"},
+		{s.SSHPassphraseEnc, &sshCfg.Passphrase, "ssh key passphrase"},
+		{s.SSHPasswordEnc, &sshCfg.Password, "ssh password"},
+		{s.APITokenEnc, &apiToken, "api token"},
+		{s.HAProxy.PasswordEnc, &settings.HAProxyPassword, "data plane API password"},
+	}
+	if s.JumpHost != "" {
+		jump := &proxy.SSHJump{Host: s.JumpHost, Port: s.JumpPort}
+		jump.User = s.JumpUser
+		if jump.Port == 0 {
+			jump.Port = 22
 		}
-		sshKey = dec
-	}
-	if s.APITokenEnc != "" {
-		dec, err := crypto.Decrypt(s.APITokenEnc)
+		if jump.User == "" {
+			jump.User = s.SSHUser
+		}
+		secrets = append(secrets,
+			secretField{s.JumpKeyEnc, &jump.PrivateKey, "jump host key"},
+			secretField{s.JumpPassphraseEnc, &jump.Passphrase, "jump host key passphrase"},
+		)
+		sshCfg.Jump = jump
+	}
+	for _, f := range secrets {
+		if f.enc == "" {
+			continue
+		}
+		dec, err := crypto.Decrypt(f.enc)
 		if err != nil {
-			return nil, fmt.Errorf("decrypt api token: %w", err)
+			return nil, fmt.Errorf("decrypt %s: %w", f.name, err)
 		}
-		apiToken = dec
+		*f.dst = dec
 	}
 	return proxyManager.NewAdapter(
 		s.ID, s.Name, s.Host, s.Port,
 		string(s.ProxyType), string(s.ConnectionType),
-		s.SSHUser, sshKey, s.APIURL, apiToken,
+		sshCfg, settings, s.APIURL, apiToken,
 	)
 }
 
+// secretField pairs an encrypted column with where its plaintext goes.
+type secretField struct {
+	enc  string
+	dst  *string
+	name string
+}
+
+// secretInput is a plaintext secret from a request and the column it is
+// stored in.
+type secretInput struct {
+	dst   *string
+	plain string
+}
+
+// encryptSecrets stores each plaintext encrypted in its column. Empty
+// values leave the column untouched, so omitting a secret from an update
+// keeps the stored one.
+func encryptSecrets(inputs ...secretInput) error {
+	for _, in := range inputs {
+		if in.plain == "" {
+			continue
+		}
+		enc, err := crypto.Encrypt(in.plain)
+		if err != nil {
+			return err
+		}
+		*in.dst = enc
+	}
+	return nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
 func defaultPort(proxyType string) int {
 	switch proxyType {
 	case "nginx":
@@ -509,17 +1115,3 @@ func defaultPort(proxyType string) int {
 		return 80
 	}
 }
-
-func parseLogLevel(line string) string {
-	lower := strings.ToLower(line)
-	switch {
-	case strings.Contains(lower, "error") || strings.Contains(lower, "crit") || strings.Contains(lower, "emerg"):
-		return "error"
-	case strings.Contains(lower, "warn"):
-		return "warn"
-	case strings.Contains(lower, "debug"):
-		return "debug"
-	default:
-		return "info"
-	}
-}
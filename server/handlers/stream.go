@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/anveesa/proxera/database"
+	"github.com/anveesa/proxera/models"
+)
+
+// metricsStreamer runs one GetMetrics poller per server that has at least
+// one WebSocket subscriber, however many clients are watching it.
+type metricsStreamer struct {
+	mu       sync.Mutex
+	interval time.Duration
+	subs     map[string]int
+	pollers  map[string]context.CancelFunc
+}
+
+var streamer = &metricsStreamer{
+	interval: 2 * time.Second,
+	subs:     make(map[string]int),
+	pollers:  make(map[string]context.CancelFunc),
+}
+
+// SetMetricsStreamInterval sets how often subscribed servers are sampled.
+// Pollers started afterwards use the new cadence.
+func SetMetricsStreamInterval(interval time.Duration) {
+	streamer.mu.Lock()
+	streamer.interval = interval
+	streamer.mu.Unlock()
+}
+
+// acquire registers one more subscriber for serverID and starts its poller
+// if this is the first one.
+func (s *metricsStreamer) acquire(serverID string) {
+	if serverID == "*" {
+		return // wildcard subscribers only receive what other pollers publish
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subs[serverID]++
+	if _, running := s.pollers[serverID]; running {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.pollers[serverID] = cancel
+	go s.poll(ctx, serverID, s.interval)
+}
+
+// release drops one subscriber for serverID and stops its poller when the
+// last one leaves.
+func (s *metricsStreamer) release(serverID string) {
+	if serverID == "*" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subs[serverID] == 0 {
+		return
+	}
+	s.subs[serverID]--
+	if s.subs[serverID] > 0 {
+		return
+	}
+	delete(s.subs, serverID)
+	if cancel, ok := s.pollers[serverID]; ok {
+		cancel()
+		delete(s.pollers, serverID)
+	}
+}
+
+func (s *metricsStreamer) poll(ctx context.Context, serverID string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.sample(ctx, serverID)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *metricsStreamer) sample(ctx context.Context, serverID string) {
+	var server models.Server
+	if err := database.DB.Where("id = ? AND deleted_at IS NULL", serverID).First(&server).Error; err != nil {
+		return
+	}
+	adapter, err := buildAdapter(&server)
+	if err != nil {
+		log.Printf("Metrics stream: server %s: %v", serverID, err)
+		return
+	}
+
+	sampleCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	m, err := adapter.GetMetrics(sampleCtx)
+	if err != nil || ctx.Err() != nil {
+		return
+	}
+	Hub.BroadcastMetrics(serverID, m)
+}
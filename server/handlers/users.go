@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/anveesa/proxera/auth"
+	"github.com/anveesa/proxera/database"
+	"github.com/anveesa/proxera/middleware"
+	"github.com/anveesa/proxera/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ListUsers GET /api/v1/users
+func ListUsers(c *gin.Context) {
+	var users []models.User
+	if err := database.DB.Order("created_at ASC").Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range users {
+		auth.DecodeServerTags(&users[i])
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+// CreateUser POST /api/v1/users
+func CreateUser(c *gin.Context) {
+	var req models.CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !auth.ValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+		return
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := models.User{
+		ID:             uuid.New().String(),
+		Name:           req.Name,
+		Email:          strings.ToLower(strings.TrimSpace(req.Email)),
+		PasswordHash:   hash,
+		Role:           req.Role,
+		ServerTagsJSON: "[]",
+	}
+	if req.ServerTags != nil {
+		b, _ := json.Marshal(req.ServerTags)
+		user.ServerTagsJSON = string(b)
+	}
+
+	if err := database.DB.Create(&user).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "user already exists"})
+		return
+	}
+
+	auth.DecodeServerTags(&user)
+	recordAudit(c, "create", "user", user.ID, nil, userAuditSnapshot(&user))
+	c.JSON(http.StatusCreated, user)
+}
+
+// PatchUser PATCH /api/v1/users/:id
+func PatchUser(c *gin.Context) {
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	auth.DecodeServerTags(user)
+	before := userAuditSnapshot(user)
+
+	if req.Name != nil {
+		user.Name = *req.Name
+	}
+	if req.Role != nil {
+		if !auth.ValidRole(*req.Role) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+			return
+		}
+		if user.ID == middleware.CurrentUser(c).ID && *req.Role != models.RoleAdmin {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "you cannot remove your own admin role"})
+			return
+		}
+		user.Role = *req.Role
+	}
+	passwordChanged := false
+	if req.Password != nil && *req.Password != "" {
+		hash, err := auth.HashPassword(*req.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		user.PasswordHash = hash
+		passwordChanged = true
+	}
+	if req.ServerTags != nil {
+		b, _ := json.Marshal(req.ServerTags)
+		user.ServerTagsJSON = string(b)
+	}
+
+	// A new password signs the user out everywhere, except for the session
+	// making the change when users change their own.
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(user).Error; err != nil {
+			return err
+		}
+		if !passwordChanged {
+			return nil
+		}
+		sessions := tx.Where("user_id = ?", user.ID)
+		if s := middleware.CurrentSession(c); s != nil && s.UserID == user.ID {
+			sessions = sessions.Where("id <> ?", s.ID)
+		}
+		return sessions.Delete(&models.Session{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	auth.DecodeServerTags(user)
+	recordAudit(c, "patch", "user", user.ID, before, userAuditSnapshot(user))
+	c.JSON(http.StatusOK, user)
+}
+
+// DeleteUser DELETE /api/v1/users/:id
+func DeleteUser(c *gin.Context) {
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
+	if user.ID == middleware.CurrentUser(c).ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "you cannot delete your own account"})
+		return
+	}
+
+	database.DB.Where("user_id = ?", user.ID).Delete(&models.Session{})
+	if err := database.DB.Delete(user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(c, "delete", "user", user.ID, userAuditSnapshot(user), nil)
+	c.JSON(http.StatusOK, gin.H{"message": "user deleted"})
+}
+
+// ─── Helpers ──────────────────────────────────────────────────────────────────
+
+func findUser(c *gin.Context) (*models.User, bool) {
+	id := c.Param("id")
+	var user models.User
+	if err := database.DB.First(&user, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return nil, false
+	}
+	return &user, true
+}
+
+// userAuditSnapshot includes the password hash so password changes show up
+// (redacted) in audit diffs.
+func userAuditSnapshot(u *models.User) map[string]interface{} {
+	m := auditSnapshot(u)
+	m["passwordHash"] = u.PasswordHash
+	return m
+}
@@ -7,6 +7,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/anveesa/proxera/config"
+	"github.com/anveesa/proxera/middleware"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 )
@@ -15,7 +17,7 @@ var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
 	CheckOrigin: func(r *http.Request) bool {
-		return true // CORS handled by middleware
+		return middleware.OriginAllowed(r.Header.Get("Origin"), r.Host, config.C.AllowOrigins)
 	},
 }
 
@@ -37,6 +39,7 @@ type WSClient struct {
 	send      chan []byte
 	hub       *WSHub
 	serverIDs map[string]bool
+	closed    bool // set once the hub has dropped the client
 	mu        sync.Mutex
 }
 
@@ -73,7 +76,7 @@ func (h *WSHub) run() {
 			h.mu.Lock()
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
-				close(client.send)
+				client.releaseAll()
 			}
 			h.mu.Unlock()
 
@@ -186,7 +189,10 @@ func (c *WSClient) readPump() {
 			}
 			c.mu.Lock()
 			for _, id := range sub.ServerIDs {
-				c.serverIDs[id] = true
+				if !c.closed && !c.serverIDs[id] {
+					c.serverIDs[id] = true
+					streamer.acquire(id)
+				}
 			}
 			c.mu.Unlock()
 
@@ -197,20 +203,43 @@ func (c *WSClient) readPump() {
 			}
 			c.mu.Lock()
 			for _, id := range sub.ServerIDs {
-				delete(c.serverIDs, id)
+				if c.serverIDs[id] {
+					delete(c.serverIDs, id)
+					streamer.release(id)
+				}
 			}
 			c.mu.Unlock()
 
 		case "ping":
 			pong, _ := json.Marshal(WSMessage{Type: "pong", Payload: json.RawMessage(`{}`)})
-			select {
-			case c.send <- pong:
-			default:
+			c.mu.Lock()
+			if !c.closed {
+				select {
+				case c.send <- pong:
+				default:
+				}
 			}
+			c.mu.Unlock()
 		}
 	}
 }
 
+// releaseAll drops every metrics subscription held by a departing client
+// and marks it closed, so a readPump still running takes no new ones. It
+// also closes the connection, which ends that readPump, and the send
+// channel, which ends writePump.
+func (c *WSClient) releaseAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	for id := range c.serverIDs {
+		streamer.release(id)
+	}
+	c.serverIDs = make(map[string]bool)
+	close(c.send)
+	c.conn.Close()
+}
+
 func (c *WSClient) writePump() {
 	ticker := time.NewTicker(30 * time.Second)
 	defer func() {
@@ -4,6 +4,7 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/anveesa/proxera/auth"
 	"github.com/anveesa/proxera/config"
 	"github.com/anveesa/proxera/crypto"
 	"github.com/anveesa/proxera/database"
@@ -24,6 +25,17 @@ func main() {
 		log.Fatalf("Database initialization failed: %v", err)
 	}
 
+	// Ensure there is an account to log in with
+	if err := auth.Bootstrap(config.C.AdminEmail, config.C.AdminPassword); err != nil {
+		log.Fatalf("Admin bootstrap failed: %v", err)
+	}
+
+	// Background health checks, metrics collection and access log analytics
+	handlers.StartHealthChecker(config.C.HealthCheckInterval, config.C.HealthCheckConcurrency)
+	handlers.StartMetricsCollector(config.C.MetricsInterval, config.C.MetricsRetention)
+	handlers.StartAnalyticsIngester(config.C.AnalyticsRetention)
+	handlers.SetMetricsStreamInterval(config.C.MetricsStreamInterval)
+
 	// Set Gin mode
 	if config.C.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -40,55 +52,96 @@ func main() {
 	})
 
 	// WebSocket endpoint
-	r.GET("/ws", handlers.HandleWS)
+	r.GET("/ws", middleware.RequireAuth(), middleware.RequirePermission(auth.PermMetricsRead), handlers.HandleWS)
 
 	// API v1 routes
-	v1 := r.Group("/api/v1")
+	r.POST("/api/v1/auth/login", handlers.Login)
+
+	v1 := r.Group("/api/v1", middleware.RequireAuth())
 	{
+		can := middleware.RequirePermission
+
+		// Session
+		v1.POST("/auth/logout", handlers.Logout)
+		v1.GET("/auth/me", handlers.Me)
+
+		// Users
+		users := v1.Group("/users", middleware.RequireSession(), can(auth.PermUsersManage))
+		{
+			users.GET("", handlers.ListUsers)
+			users.POST("", handlers.CreateUser)
+			users.PATCH("/:id", handlers.PatchUser)
+			users.DELETE("/:id", handlers.DeleteUser)
+		}
+
+		// API keys (session only, so a key cannot mint more keys)
+		apiKeys := v1.Group("/api-keys", middleware.RequireSession())
+		{
+			apiKeys.GET("", handlers.ListAPIKeys)
+			apiKeys.POST("", handlers.CreateAPIKey)
+			apiKeys.DELETE("/:id", handlers.RevokeAPIKey)
+		}
+
+		// Audit log
+		v1.GET("/audit", can(auth.PermAuditRead), handlers.ListAuditEvents)
+
+		// Access log analytics
+		v1.GET("/analytics", can(auth.PermMetricsRead), handlers.GetAnalytics)
+
 		// Servers
 		servers := v1.Group("/servers")
 		{
-			servers.GET("", handlers.ListServers)
-			servers.POST("", handlers.CreateServer)
-			servers.GET("/:id", handlers.GetServer)
-			servers.PUT("/:id", handlers.UpdateServer)
-			servers.PATCH("/:id", handlers.PatchServer)
-			servers.DELETE("/:id", handlers.DeleteServer)
-			servers.GET("/:id/health", handlers.ServerHealth)
-			servers.GET("/:id/metrics", handlers.ServerMetrics)
-			servers.GET("/:id/config", handlers.GetServerConfig)
-			servers.PUT("/:id/config", handlers.PutServerConfig)
-			servers.POST("/:id/reload", handlers.ReloadServer)
-			servers.GET("/:id/logs", handlers.StreamServerLogs)
+			servers.GET("", can(auth.PermServersRead), handlers.ListServers)
+			servers.POST("", can(auth.PermServersWrite), handlers.CreateServer)
+			servers.GET("/:id", can(auth.PermServersRead), handlers.GetServer)
+			servers.PUT("/:id", can(auth.PermServersWrite), handlers.UpdateServer)
+			servers.PATCH("/:id", can(auth.PermServersWrite), handlers.PatchServer)
+			servers.DELETE("/:id", can(auth.PermServersWrite), handlers.DeleteServer)
+			servers.GET("/:id/health", can(auth.PermServersRead), handlers.ServerHealth)
+			servers.GET("/:id/metrics", can(auth.PermMetricsRead), handlers.ServerMetrics)
+			servers.GET("/:id/config", can(auth.PermConfigRead), handlers.GetServerConfig)
+			servers.PUT("/:id/config", can(auth.PermConfigWrite), handlers.PutServerConfig)
+			servers.POST("/:id/config/changes", can(auth.PermConfigWrite), handlers.ApplyServerConfigChanges)
+			servers.POST("/:id/backends/:backend/servers/:name/state", can(auth.PermConfigWrite), handlers.SetBackendServerState)
+			servers.GET("/:id/config/revisions", can(auth.PermConfigRead), handlers.ListConfigRevisions)
+			servers.GET("/:id/config/revisions/:rev", can(auth.PermConfigRead), handlers.GetConfigRevision)
+			servers.POST("/:id/config/revisions/:rev/rollback", can(auth.PermConfigWrite), handlers.RollbackConfigRevision)
+			servers.GET("/:id/config/diff", can(auth.PermConfigRead), handlers.DiffConfigRevisions)
+			servers.POST("/:id/reload", can(auth.PermConfigWrite), handlers.ReloadServer)
+			servers.GET("/:id/host-key", can(auth.PermServersRead), handlers.GetHostKey)
+			servers.POST("/:id/host-key/approve", can(auth.PermServersWrite), handlers.ApproveHostKey)
+			servers.DELETE("/:id/host-key", can(auth.PermServersWrite), handlers.ResetHostKey)
+			servers.GET("/:id/logs", can(auth.PermLogsRead), handlers.StreamServerLogs)
+			servers.POST("/:id/routes/sync", can(auth.PermConfigWrite), handlers.SyncServerRoutes)
 		}
 
 		// Routes
 		routes := v1.Group("/routes")
 		{
-			routes.GET("", handlers.ListRoutes)
-			routes.POST("", handlers.CreateRoute)
-			routes.GET("/:id", handlers.GetRoute)
-			routes.PUT("/:id", handlers.UpdateRoute)
-			routes.PATCH("/:id", handlers.PatchRoute)
-			routes.DELETE("/:id", handlers.DeleteRoute)
-			routes.POST("/:id/toggle", handlers.ToggleRoute)
+			routes.GET("", can(auth.PermRoutesRead), handlers.ListRoutes)
+			routes.POST("", can(auth.PermRoutesWrite), handlers.CreateRoute)
+			routes.GET("/:id", can(auth.PermRoutesRead), handlers.GetRoute)
+			routes.PUT("/:id", can(auth.PermRoutesWrite), handlers.UpdateRoute)
+			routes.PATCH("/:id", can(auth.PermRoutesWrite), handlers.PatchRoute)
+			routes.DELETE("/:id", can(auth.PermRoutesWrite), handlers.DeleteRoute)
+			routes.POST("/:id/toggle", can(auth.PermRoutesWrite), handlers.ToggleRoute)
 		}
 
 		// Alerts
 		alerts := v1.Group("/alerts")
 		{
-			alerts.GET("", handlers.ListAlerts)
-			alerts.POST("", handlers.CreateAlert)
-			alerts.GET("/:id", handlers.GetAlert)
-			alerts.PATCH("/:id", handlers.UpdateAlert)
-			alerts.DELETE("/:id", handlers.DeleteAlert)
-			alerts.POST("/:id/acknowledge", handlers.AcknowledgeAlert)
-			alerts.POST("/:id/resolve", handlers.ResolveAlert)
-			alerts.POST("/bulk/acknowledge", handlers.BulkAcknowledgeAlerts)
+			alerts.GET("", can(auth.PermAlertsRead), handlers.ListAlerts)
+			alerts.POST("", can(auth.PermAlertsWrite), handlers.CreateAlert)
+			alerts.GET("/:id", can(auth.PermAlertsRead), handlers.GetAlert)
+			alerts.PATCH("/:id", can(auth.PermAlertsWrite), handlers.UpdateAlert)
+			alerts.DELETE("/:id", can(auth.PermAlertsWrite), handlers.DeleteAlert)
+			alerts.POST("/:id/acknowledge", can(auth.PermAlertsWrite), handlers.AcknowledgeAlert)
+			alerts.POST("/:id/resolve", can(auth.PermAlertsWrite), handlers.ResolveAlert)
+			alerts.POST("/bulk/acknowledge", can(auth.PermAlertsWrite), handlers.BulkAcknowledgeAlerts)
 		}
 
 		// Dashboard
-		dashboard := v1.Group("/dashboard")
+		dashboard := v1.Group("/dashboard", can(auth.PermMetricsRead))
 		{
 			dashboard.GET("/stats", handlers.DashboardStats)
 			dashboard.GET("/traffic", handlers.DashboardTraffic)
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/anveesa/proxera/auth"
+	"github.com/anveesa/proxera/models"
+	"github.com/gin-gonic/gin"
+)
+
+// SessionCookie is the cookie carrying the session token for browsers.
+const SessionCookie = "proxera_session"
+
+const (
+	userKey    = "user"
+	sessionKey = "session"
+	apiKeyKey  = "apiKey"
+)
+
+// RequireAuth rejects requests without a valid session token or API key.
+// The credential is read from "Authorization: Bearer", the session cookie,
+// or — for WebSocket upgrades only, since browsers cannot set headers
+// there — a ?token= query. API keys act as their owner, narrowed to the
+// key's scopes.
+func RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := RequestToken(c)
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+
+		if auth.IsAPIKey(token) {
+			user, key, err := auth.AuthenticateAPIKey(token)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+			c.Set(userKey, user)
+			c.Set(apiKeyKey, key)
+			c.Next()
+			return
+		}
+
+		user, session, err := auth.Authenticate(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.Set(userKey, user)
+		c.Set(sessionKey, session)
+		c.Next()
+	}
+}
+
+// RequirePermission rejects callers whose role does not grant perm, or
+// whose API key lacks it as a scope. It must run after RequireAuth.
+func RequirePermission(perm auth.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasPermission(c, perm) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden: requires " + string(perm)})
+			return
+		}
+		c.Next()
+	}
+}
+
+// HasPermission reports whether the caller's role grants perm and, for
+// API key requests, the key carries it as a scope.
+func HasPermission(c *gin.Context, perm auth.Permission) bool {
+	user := CurrentUser(c)
+	if user == nil || !auth.HasPermission(user.Role, perm) {
+		return false
+	}
+	if key := CurrentAPIKey(c); key != nil && !auth.KeyAllows(key, perm) {
+		return false
+	}
+	return true
+}
+
+// RequireSession rejects requests authenticated with an API key, for
+// endpoints only a human should reach (e.g. managing keys).
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if CurrentAPIKey(c) != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden: not available to API keys"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequestToken extracts the session token from the request, if any.
+func RequestToken(c *gin.Context) string {
+	if h := c.GetHeader("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+	}
+	if cookie, err := c.Cookie(SessionCookie); err == nil && cookie != "" {
+		return cookie
+	}
+	if strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+		return c.Query("token")
+	}
+	return ""
+}
+
+// CurrentUser returns the authenticated user set by RequireAuth.
+func CurrentUser(c *gin.Context) *models.User {
+	if v, ok := c.Get(userKey); ok {
+		if u, ok := v.(*models.User); ok {
+			return u
+		}
+	}
+	return nil
+}
+
+// CurrentSession returns the session used to authenticate, or nil for API
+// key requests.
+func CurrentSession(c *gin.Context) *models.Session {
+	if v, ok := c.Get(sessionKey); ok {
+		if s, ok := v.(*models.Session); ok {
+			return s
+		}
+	}
+	return nil
+}
+
+// CurrentAPIKey returns the API key used to authenticate, or nil for
+// session-authenticated requests.
+func CurrentAPIKey(c *gin.Context) *models.APIKey {
+	if v, ok := c.Get(apiKeyKey); ok {
+		if k, ok := v.(*models.APIKey); ok {
+			return k
+		}
+	}
+	return nil
+}
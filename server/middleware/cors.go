@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"net/url"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -30,3 +31,21 @@ func CORS(allowOrigins string) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// OriginAllowed reports whether a browser Origin may open a WebSocket.
+// Requests without an Origin (non-browser clients) and same-host origins
+// are always allowed; anything else must be listed in allowOrigins.
+func OriginAllowed(origin, host, allowOrigins string) bool {
+	if origin == "" || allowOrigins == "*" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && u.Host == host {
+		return true
+	}
+	for _, o := range strings.Split(allowOrigins, ",") {
+		if strings.TrimSpace(o) == origin {
+			return true
+		}
+	}
+	return false
+}
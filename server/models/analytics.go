@@ -0,0 +1,97 @@
+package models
+
+import "time"
+
+// AnalyticsBucket aggregates one server's access log lines over one time
+// bucket. Endpoints, hosts and the latency histogram are stored as JSON.
+type AnalyticsBucket struct {
+	ID           uint      `gorm:"primaryKey" json:"-"`
+	ServerID     string    `gorm:"not null;uniqueIndex:idx_analytics_bucket,priority:1" json:"serverId"`
+	Start        time.Time `gorm:"not null;uniqueIndex:idx_analytics_bucket,priority:2;index" json:"start"`
+	Requests     int64     `json:"requests"`
+	Status1xx    int64     `json:"status1xx"`
+	Status2xx    int64     `json:"status2xx"`
+	Status3xx    int64     `json:"status3xx"`
+	Status4xx    int64     `json:"status4xx"`
+	Status5xx    int64     `json:"status5xx"`
+	BytesSent    int64     `json:"bytesSent"`
+	LatencyCount int64     `json:"latencyCount"` // requests that logged a duration
+	LatencySum   float64   `json:"latencySum"`   // ms
+
+	LatencyJSON   string                     `gorm:"column:latency" json:"-"`
+	Latency       []int64                    `gorm:"-" json:"latency"` // counts per AnalyticsLatencyBounds bucket, plus overflow
+	EndpointsJSON string                     `gorm:"column:endpoints" json:"-"`
+	Endpoints     map[string]*EndpointCounts `gorm:"-" json:"endpoints"` // by "METHOD /path"
+	HostsJSON     string                     `gorm:"column:hosts" json:"-"`
+	Hosts         map[string]int64           `gorm:"-" json:"hosts"`
+}
+
+// AnalyticsLatencyBounds are the upper bounds, in ms, of the latency
+// histogram buckets; a last bucket counts everything slower.
+var AnalyticsLatencyBounds = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// EndpointCounts is one endpoint's traffic within a bucket.
+type EndpointCounts struct {
+	Requests     int64   `json:"requests"`
+	Errors       int64   `json:"errors"` // 5xx
+	LatencyCount int64   `json:"latencyCount"`
+	LatencySum   float64 `json:"latencySum"` // ms
+}
+
+// AnalyticsReport is the GET /api/v1/analytics response.
+type AnalyticsReport struct {
+	Since        time.Time          `json:"since"`
+	Until        time.Time          `json:"until"`
+	Requests     int64              `json:"requests"`
+	Errors       int64              `json:"errors"`
+	ErrorRate    float64            `json:"errorRate"`
+	BytesSent    int64              `json:"bytesSent"`
+	Latency      LatencyPercentiles `json:"latency"`
+	StatusCodes  []StatusClassCount `json:"statusCodes"`
+	TopEndpoints []EndpointStats    `json:"topEndpoints"`
+	TopHosts     []HostStats        `json:"topHosts"`
+	Servers      []ServerAnalytics  `json:"servers"`
+	Series       []AnalyticsPoint   `json:"series"`
+}
+
+type LatencyPercentiles struct {
+	Avg float64 `json:"avg"`
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+}
+
+type StatusClassCount struct {
+	Class   string  `json:"class"` // "2xx", ...
+	Count   int64   `json:"count"`
+	Percent float64 `json:"percent"`
+}
+
+type EndpointStats struct {
+	Endpoint  string  `json:"endpoint"` // "METHOD /path"
+	Requests  int64   `json:"requests"`
+	ErrorRate float64 `json:"errorRate"`
+	AvgMs     float64 `json:"avgMs"`
+}
+
+type HostStats struct {
+	Host     string `json:"host"`
+	Requests int64  `json:"requests"`
+}
+
+// ServerAnalytics is one server's share of a report.
+type ServerAnalytics struct {
+	ServerID  string             `json:"serverId"`
+	Requests  int64              `json:"requests"`
+	ErrorRate float64            `json:"errorRate"`
+	Latency   LatencyPercentiles `json:"latency"`
+}
+
+type AnalyticsPoint struct {
+	Time      time.Time `json:"time"`
+	Requests  int64     `json:"requests"`
+	Errors    int64     `json:"errors"`
+	BytesSent int64     `json:"bytesSent"`
+	P50       float64   `json:"p50"`
+	P95       float64   `json:"p95"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// APIKey is a long-lived credential for automation. Only the SHA-256 of
+// the key is stored; Prefix keeps enough of it to tell keys apart in the UI.
+type APIKey struct {
+	ID         string     `gorm:"primaryKey;type:text" json:"id"`
+	UserID     string     `gorm:"not null;index" json:"userId"`
+	Name       string     `gorm:"not null" json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `gorm:"not null;uniqueIndex" json:"-"`
+	ScopesJSON string     `gorm:"column:scopes;default:'[]'" json:"-"`
+	Scopes     []string   `gorm:"-" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// CreateAPIKeyResponse carries the plaintext key; it is only ever returned
+// once, at creation.
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
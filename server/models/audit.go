@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// AuditEvent records one mutating API operation. Rows are append-only.
+type AuditEvent struct {
+	ID           string                 `gorm:"primaryKey;type:text" json:"id"`
+	ActorID      string                 `gorm:"index" json:"actorId"`
+	Actor        string                 `json:"actor"`
+	APIKeyID     string                 `json:"apiKeyId,omitempty"`
+	Action       string                 `gorm:"not null;index" json:"action"`
+	ResourceType string                 `gorm:"not null;index:idx_audit_resource,priority:1" json:"resourceType"`
+	ResourceID   string                 `gorm:"index:idx_audit_resource,priority:2" json:"resourceId"`
+	SourceIP     string                 `json:"sourceIp"`
+	DiffJSON     string                 `gorm:"column:diff" json:"-"`
+	Diff         map[string]AuditChange `gorm:"-" json:"diff,omitempty"`
+	CreatedAt    time.Time              `gorm:"index" json:"timestamp"`
+}
+
+// AuditChange is one field's value before and after an operation.
+type AuditChange struct {
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// SSHHostKey is the host key pinned for one SSH hop of a server: the
+// server itself or its jump host. A key presented later that differs from
+// the pinned one is kept as pending until an admin approves it; connections
+// are refused meanwhile.
+type SSHHostKey struct {
+	ID          string     `gorm:"primaryKey;type:text" json:"-"` // proxy.HostKeyID
+	ServerID    string     `gorm:"not null;index" json:"serverId"`
+	Hop         string     `gorm:"not null" json:"hop"` // target or jump
+	Address     string     `json:"address"`
+	KeyType     string     `json:"keyType"`
+	Fingerprint string     `json:"fingerprint"`
+	PublicKey   string     `json:"publicKey"` // authorized_keys format
+	ApprovedBy  string     `json:"approvedBy,omitempty"`
+	ApprovedAt  *time.Time `json:"approvedAt,omitempty"`
+
+	PendingKeyType     string     `json:"pendingKeyType,omitempty"`
+	PendingFingerprint string     `json:"pendingFingerprint,omitempty"`
+	PendingPublicKey   string     `json:"pendingPublicKey,omitempty"`
+	PendingSeenAt      *time.Time `json:"pendingSeenAt,omitempty"`
+
+	CreatedAt time.Time `json:"firstSeen"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type ApproveHostKeyRequest struct {
+	// Fingerprint must match the pending key, so an admin approves exactly
+	// the key they inspected.
+	Fingerprint string `json:"fingerprint" binding:"required"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Log kinds.
+const (
+	LogKindAccess = "access"
+	LogKindError  = "error"
+)
+
+// LogEntry is one parsed proxy log line. Fields the line does not carry
+// are left empty; Message is always the line as read.
+type LogEntry struct {
+	Timestamp time.Time `json:"timestamp"`      // zero if the line has none
+	Kind      string    `json:"kind,omitempty"` // empty if the line's format is unknown
+	Level     string    `json:"level"`          // debug, info, warn or error
+	ClientIP  string    `json:"remoteAddr,omitempty"`
+	Method    string    `json:"method,omitempty"`
+	Host      string    `json:"host,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Status    int       `json:"statusCode,omitempty"`
+	Bytes     int64     `json:"bytesOut,omitempty"`     // response body size
+	Duration  *float64  `json:"responseTime,omitempty"` // ms; nil if not logged
+	Upstream  string    `json:"upstream,omitempty"`
+	Message   string    `json:"message"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+type MetricResolution string
+
+const (
+	ResolutionRaw    MetricResolution = "raw"
+	ResolutionMinute MetricResolution = "1m"
+	ResolutionHour   MetricResolution = "1h"
+)
+
+// MetricSample is a persisted metrics data point. Raw rows hold a single
+// collector sample; 1m and 1h rows are rollups that average every raw
+// sample falling into the bucket starting at Timestamp.
+type MetricSample struct {
+	ID                uint             `gorm:"primaryKey" json:"-"`
+	ServerID          string           `gorm:"not null;uniqueIndex:idx_metric_bucket,priority:1" json:"serverId"`
+	Resolution        MetricResolution `gorm:"not null;uniqueIndex:idx_metric_bucket,priority:2" json:"resolution"`
+	Timestamp         time.Time        `gorm:"not null;uniqueIndex:idx_metric_bucket,priority:3;index" json:"timestamp"`
+	Samples           int              `json:"samples"`
+	Requests          float64          `json:"requests"` // estimated request count in the bucket
+	Errors            float64          `json:"errors"`   // estimated error count in the bucket
+	RequestsPerSec    float64          `json:"requestsPerSec"`
+	ActiveConnections float64          `json:"activeConnections"`
+	ErrorRate         float64          `json:"errorRate"`
+	P50Latency        float64          `json:"p50Latency"`
+	P95Latency        float64          `json:"p95Latency"`
+	P99Latency        float64          `json:"p99Latency"`
+	CPUUsage          float64          `json:"cpuUsage"`
+	MemUsage          float64          `json:"memUsage"`
+	NetworkIn         float64          `json:"networkIn"`
+	NetworkOut        float64          `json:"networkOut"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Revision sources.
+const (
+	RevisionSourcePut          = "put"
+	RevisionSourceChanges      = "changes" // object-level edits through the proxy's API
+	RevisionSourceSync         = "routes_sync"
+	RevisionSourceRollback     = "rollback"
+	RevisionSourceAutoRollback = "auto_rollback" // restored by a failed guarded apply
+	RevisionSourceObserved     = "observed"      // found on the proxy, changed outside Proxera
+)
+
+// ConfigRevision is one snapshot of a server's proxy configuration. Revision
+// numbers increase per server, starting at 1.
+type ConfigRevision struct {
+	ID        string       `gorm:"primaryKey;type:text" json:"id"`
+	ServerID  string       `gorm:"not null;uniqueIndex:idx_revision_server_rev,priority:1" json:"serverId"`
+	Revision  int          `gorm:"not null;uniqueIndex:idx_revision_server_rev,priority:2" json:"revision"`
+	Source    string       `gorm:"not null" json:"source"`
+	Author    string       `json:"author,omitempty"`
+	Checksum  string       `json:"checksum"`
+	Size      int          `json:"size"`
+	Content   string       `json:"content,omitempty"`
+	FilesJSON string       `gorm:"column:files" json:"-"`
+	Files     []ConfigFile `gorm:"-" json:"files,omitempty"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
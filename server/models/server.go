@@ -40,8 +40,29 @@ type Server struct {
 	Tags           []string       `gorm:"-" json:"tags"`
 
 	// SSH fields
-	SSHUser       string `json:"sshUser,omitempty"`
-	SSHKeyContent string `gorm:"column:ssh_key_enc" json:"-"` // stored encrypted
+	SSHUser          string `json:"sshUser,omitempty"`
+	SSHKeyContent    string `gorm:"column:ssh_key_enc" json:"-"`        // stored encrypted
+	SSHPassphraseEnc string `gorm:"column:ssh_passphrase_enc" json:"-"` // stored encrypted
+	SSHPasswordEnc   string `gorm:"column:ssh_password_enc" json:"-"`   // stored encrypted
+
+	// Jump host (bastion) the SSH connection is tunnelled through
+	JumpHost          string `json:"jumpHost,omitempty"`
+	JumpPort          int    `json:"jumpPort,omitempty"`
+	JumpUser          string `json:"jumpUser,omitempty"`
+	JumpKeyEnc        string `gorm:"column:jump_key_enc" json:"-"`        // stored encrypted
+	JumpPassphraseEnc string `gorm:"column:jump_passphrase_enc" json:"-"` // stored encrypted
+
+	// NGINX servers: where things live on the host and how to drive it
+	NGINX NGINXSettings `gorm:"embedded;embeddedPrefix:nginx_" json:"nginx"`
+
+	// Traefik servers: the file provider directory Proxera writes to
+	Traefik TraefikSettings `gorm:"embedded;embeddedPrefix:traefik_" json:"traefik"`
+
+	// HAProxy servers: the Data Plane API used for config writes
+	HAProxy HAProxySettings `gorm:"embedded;embeddedPrefix:haproxy_" json:"haproxy"`
+
+	// Where the proxy's logs are read from on its host
+	Logs LogSettings `gorm:"embedded;embeddedPrefix:log_" json:"logs"`
 
 	// API fields
 	APIURL       string `json:"apiUrl,omitempty"`
@@ -61,6 +82,94 @@ type Server struct {
 	LastChecked *time.Time `json:"lastChecked,omitempty"`
 }
 
+// NGINXSettings describes a non-standard NGINX install: a custom prefix,
+// OpenResty, or nginx running in a container. Empty fields fall back to a
+// stock package install.
+type NGINXSettings struct {
+	Binary        string `json:"binary,omitempty"`        // default "nginx", e.g. "openresty"
+	ConfigPath    string `json:"configPath,omitempty"`    // default: the binary's --conf-path
+	AccessLogPath string `json:"accessLogPath,omitempty"` // default /var/log/nginx/access.log
+	ErrorLogPath  string `json:"errorLogPath,omitempty"`  // default /var/log/nginx/error.log
+	StatusURL     string `json:"statusUrl,omitempty"`     // stub_status, fetched from the host
+	TestCommand   string `json:"testCommand,omitempty"`   // default "<binary> -t"; run with -c <file> appended
+	ReloadCommand string `json:"reloadCommand,omitempty"` // default "<binary> -s reload"
+	// UseSudo runs commands through sudo; unset means true.
+	UseSudo *bool `gorm:"default:true" json:"useSudo,omitempty"`
+	// DockerContainer, if set, runs every command inside that container
+	// via `docker exec`.
+	DockerContainer string `json:"dockerContainer,omitempty"`
+}
+
+// Ways Proxera reaches a Traefik file provider directory.
+const (
+	FileAccessSSH   = "ssh"   // over SSH, with the server's SSH credentials
+	FileAccessLocal = "local" // a directory mounted into Proxera itself
+)
+
+// TraefikSettings makes a Traefik server writable. Proxera writes one file
+// into the directory watched by Traefik's file provider and lets Traefik
+// pick it up; with no FileDir the server is read-only.
+type TraefikSettings struct {
+	FileDir    string `json:"fileDir,omitempty"`
+	FileName   string `json:"fileName,omitempty"`   // default proxera.yml; a .toml name selects TOML
+	FileAccess string `json:"fileAccess,omitempty"` // ssh (default) or local
+	SSHPort    int    `json:"sshPort,omitempty"`    // default 22
+	// UseSudo writes through sudo over SSH; unset means true.
+	UseSudo *bool `gorm:"default:true" json:"useSudo,omitempty"`
+
+	// MetricsURL is Traefik's Prometheus endpoint, for when it is served on
+	// an entrypoint other than the API's. Default: <API URL>/metrics.
+	MetricsURL string `json:"metricsUrl,omitempty"`
+}
+
+// HAProxySettings locates HAProxy's Data Plane API, which can be on a
+// different port than the stats page the server's API URL points at.
+type HAProxySettings struct {
+	DataPlaneURL string `json:"dataPlaneUrl,omitempty"` // default: the server's API URL
+	APIVersion   string `json:"apiVersion,omitempty"`   // v2 or v3; detected when empty
+	// Username and password for basic auth, the Data Plane API default.
+	// Without a username the server's API token is sent as a bearer token.
+	Username    string `json:"username,omitempty"`
+	PasswordEnc string `json:"-"` // stored encrypted
+	// StatsSocket is the path of HAProxy's admin-level stats socket on the
+	// host, reached over SSH like the logs. Weight changes need it; the SSH
+	// user needs write access to it, e.g. through the haproxy group.
+	StatsSocket string `json:"statsSocket,omitempty"`
+}
+
+// HAProxySettingsRequest is HAProxySettings as submitted, with the
+// plaintext password. An empty password keeps the stored one.
+type HAProxySettingsRequest struct {
+	HAProxySettings
+	Password string `json:"password"`
+}
+
+// Ways Proxera reads a server's logs on its host.
+const (
+	LogSourceFile    = "file"    // tail log files
+	LogSourceJournal = "journal" // follow a systemd unit's journal
+	LogSourceDocker  = "docker"  // follow a container's output
+)
+
+// LogSettings says where a server's logs come from. They are read over SSH
+// with the server's SSH credentials, whether the proxy itself is managed
+// over SSH or through its API. With no Source, NGINX servers tail their
+// access and error logs and other servers have no logs.
+type LogSettings struct {
+	Source        string `json:"source,omitempty"`        // file, journal or docker
+	AccessLogPath string `json:"accessLogPath,omitempty"` // file: at least one of the two
+	ErrorLogPath  string `json:"errorLogPath,omitempty"`
+	Unit          string `json:"unit,omitempty"`      // journal: systemd unit, e.g. caddy.service
+	Container     string `json:"container,omitempty"` // docker: container name or ID
+	// SSHPort for servers managed through their API; default 22. Servers
+	// managed over SSH use their own port.
+	SSHPort int `json:"sshPort,omitempty"`
+	// UseSudo runs the command through sudo; unset means false. Without
+	// it, the SSH user needs read access to the files, or membership of
+	// the adm or docker group.
+	UseSudo *bool `json:"useSudo,omitempty"`
+}
+
 type ServerMetrics struct {
 	ServerID          string    `json:"serverId"`
 	Timestamp         time.Time `json:"timestamp"`
@@ -72,19 +181,88 @@ type ServerMetrics struct {
 	P99Latency        float64   `json:"p99Latency"`
 	CPUUsage          float64   `json:"cpuUsage"`
 	MemUsage          float64   `json:"memUsage"`
-	NetworkIn         float64   `json:"networkIn"`
-	NetworkOut        float64   `json:"networkOut"`
+	NetworkIn         float64   `json:"networkIn"`  // bytes/s received
+	NetworkOut        float64   `json:"networkOut"` // bytes/s sent
+	// Cumulative marks a server's first sample, taken with no earlier one
+	// to diff against: it has no rates, and its error rate and latencies
+	// cover the proxy's lifetime rather than the time since the last poll.
+	Cumulative bool `json:"cumulative,omitempty"`
+
+	// Connections is the connection breakdown, for proxies that report it.
+	Connections *ConnectionStats `json:"connections,omitempty"`
+
+	// Details breaks the totals down per frontend/backend (or entrypoint,
+	// service, ...) for proxies that report them.
+	Details []MetricsDetail `json:"details,omitempty"`
+	// Resources counts the proxy's routing objects and their problems, for
+	// proxies that report them.
+	Resources []ResourceSummary `json:"resources,omitempty"`
+}
+
+// ConnectionStats is NGINX's stub_status: counters since start and the
+// current connections by state.
+type ConnectionStats struct {
+	Accepted int64 `json:"accepted"`
+	Handled  int64 `json:"handled"`
+	Dropped  int64 `json:"dropped"` // accepted - handled: refused at worker_connections or a resource limit
+	Requests int64 `json:"requests"`
+	Reading  int   `json:"reading"`
+	Writing  int   `json:"writing"`
+	Waiting  int   `json:"waiting"` // idle keep-alive
+}
+
+// MetricsDetail is one proxy-level section's share of a server's metrics.
+type MetricsDetail struct {
+	Kind              string  `json:"kind"` // "frontend", "backend", ...
+	Name              string  `json:"name"`
+	Status            string  `json:"status,omitempty"`
+	RequestsPerSec    float64 `json:"requestsPerSec"`
+	ActiveConnections int     `json:"activeConnections"`
+	ErrorRate         float64 `json:"errorRate"`       // % of requests
+	AvgResponseTime   float64 `json:"avgResponseTime"` // ms, to the first response byte
+	AvgTotalTime      float64 `json:"avgTotalTime"`    // ms, whole session
+	NetworkIn         float64 `json:"networkIn"`       // bytes/s
+	NetworkOut        float64 `json:"networkOut"`      // bytes/s
+}
+
+// ResourceSummary counts one kind of routing object, such as HTTP routers,
+// and how many of them the proxy reports warnings or errors for.
+type ResourceSummary struct {
+	Protocol string `json:"protocol"` // http, tcp, udp
+	Kind     string `json:"kind"`     // routers, services, middlewares
+	Total    int    `json:"total"`
+	Warnings int    `json:"warnings"`
+	Errors   int    `json:"errors"`
 }
 
 type ProxyConfig struct {
-	ServerID         string    `json:"serverId"`
-	ServerName       string    `json:"serverName"`
-	ProxyType        ProxyType `json:"proxyType"`
-	Content          string    `json:"content"`
-	Format           string    `json:"format"`
-	LastModified     string    `json:"lastModified"`
-	IsValid          bool      `json:"isValid"`
-	ValidationErrors []string  `json:"validationErrors"`
+	ServerID         string       `json:"serverId"`
+	ServerName       string       `json:"serverName"`
+	ProxyType        ProxyType    `json:"proxyType"`
+	Content          string       `json:"content"`
+	Files            []ConfigFile `json:"files,omitempty"` // whole tree, main file first, for multi-file proxies
+	Format           string       `json:"format"`
+	LastModified     string       `json:"lastModified"`
+	IsValid          bool         `json:"isValid"`
+	ValidationErrors []string     `json:"validationErrors"`
+}
+
+// ConfigFile is one file of a multi-file proxy configuration. In a write,
+// Delete removes the file instead.
+type ConfigFile struct {
+	Path    string `json:"path" binding:"required"`
+	Content string `json:"content"`
+	Delete  bool   `json:"delete,omitempty"`
+}
+
+// ConfigChange is one object-level edit made through a proxy's
+// configuration API, such as adding an HAProxy server to a backend.
+type ConfigChange struct {
+	Op     string         `json:"op" binding:"required,oneof=create replace delete"`
+	Kind   string         `json:"kind" binding:"required,oneof=frontend backend server"`
+	Name   string         `json:"name" binding:"required"`
+	Parent string         `json:"parent,omitempty"` // backend of a server
+	Data   map[string]any `json:"data,omitempty"`   // the object, for create and replace
 }
 
 type ConfigValidation struct {
@@ -92,32 +270,113 @@ type ConfigValidation struct {
 	Errors  []string `json:"errors"`
 }
 
+// Admin states a load-balanced backend server can be put in at runtime.
+const (
+	AdminStateReady = "ready" // takes traffic
+	AdminStateDrain = "drain" // finishes current sessions, takes no new ones
+	AdminStateMaint = "maint" // out of rotation, connections cut
+)
+
+// ServerStateChange is a runtime change to one backend server, applied
+// without a reload. Unset fields are left alone.
+type ServerStateChange struct {
+	AdminState string `json:"adminState" binding:"omitempty,oneof=ready drain maint"`
+	Weight     *int   `json:"weight" binding:"omitempty,min=0,max=256"`
+}
+
+// BackendServerState is a backend server's runtime state as the proxy
+// reports it.
+type BackendServerState struct {
+	Backend          string `json:"backend"`
+	Name             string `json:"name"`
+	Address          string `json:"address"`
+	Port             int    `json:"port,omitempty"`
+	AdminState       string `json:"adminState"`
+	OperationalState string `json:"operationalState"`
+	Weight           *int   `json:"weight,omitempty"`
+}
+
 type CreateServerRequest struct {
-	Name           string         `json:"name" binding:"required"`
-	Host           string         `json:"host" binding:"required"`
-	Port           int            `json:"port"`
-	ProxyType      ProxyType      `json:"proxyType" binding:"required"`
-	ConnectionType ConnectionType `json:"connectionType" binding:"required"`
-	Location       string         `json:"location"`
-	Description    string         `json:"description"`
-	Tags           []string       `json:"tags"`
-	SSHUser        string         `json:"sshUser"`
-	SSHKey         string         `json:"sshKey"`
-	APIURL         string         `json:"apiUrl"`
-	APIToken       string         `json:"apiToken"`
+	Name           string                  `json:"name" binding:"required"`
+	Host           string                  `json:"host" binding:"required"`
+	Port           int                     `json:"port"`
+	ProxyType      ProxyType               `json:"proxyType" binding:"required"`
+	ConnectionType ConnectionType          `json:"connectionType" binding:"required"`
+	Location       string                  `json:"location"`
+	Description    string                  `json:"description"`
+	Tags           []string                `json:"tags"`
+	SSHUser        string                  `json:"sshUser"`
+	SSHKey         string                  `json:"sshKey"`
+	SSHPassphrase  string                  `json:"sshPassphrase"`
+	SSHPassword    string                  `json:"sshPassword"`
+	JumpHost       string                  `json:"jumpHost"`
+	JumpPort       int                     `json:"jumpPort"`
+	JumpUser       string                  `json:"jumpUser"`
+	JumpKey        string                  `json:"jumpKey"`
+	JumpPassphrase string                  `json:"jumpPassphrase"`
+	NGINX          *NGINXSettings          `json:"nginx"`   // replaces the stored settings when set
+	Traefik        *TraefikSettings        `json:"traefik"` // replaces the stored settings when set
+	HAProxy        *HAProxySettingsRequest `json:"haproxy"` // replaces the stored settings when set
+	Logs           *LogSettings            `json:"logs"`    // replaces the stored settings when set
+	APIURL         string                  `json:"apiUrl"`
+	APIToken       string                  `json:"apiToken"`
 }
 
 type UpdateServerRequest struct {
-	Name           *string         `json:"name"`
-	Host           *string         `json:"host"`
-	Port           *int            `json:"port"`
-	ProxyType      *ProxyType      `json:"proxyType"`
-	ConnectionType *ConnectionType `json:"connectionType"`
-	Location       *string         `json:"location"`
-	Description    *string         `json:"description"`
-	Tags           []string        `json:"tags"`
-	SSHUser        *string         `json:"sshUser"`
-	SSHKey         *string         `json:"sshKey"`
-	APIURL         *string         `json:"apiUrl"`
-	APIToken       *string         `json:"apiToken"`
+	Name           *string                 `json:"name"`
+	Host           *string                 `json:"host"`
+	Port           *int                    `json:"port"`
+	ProxyType      *ProxyType              `json:"proxyType"`
+	ConnectionType *ConnectionType         `json:"connectionType"`
+	Location       *string                 `json:"location"`
+	Description    *string                 `json:"description"`
+	Tags           []string                `json:"tags"`
+	SSHUser        *string                 `json:"sshUser"`
+	SSHKey         *string                 `json:"sshKey"`
+	SSHPassphrase  *string                 `json:"sshPassphrase"`
+	SSHPassword    *string                 `json:"sshPassword"`
+	JumpHost       *string                 `json:"jumpHost"`
+	JumpPort       *int                    `json:"jumpPort"`
+	JumpUser       *string                 `json:"jumpUser"`
+	JumpKey        *string                 `json:"jumpKey"`
+	JumpPassphrase *string                 `json:"jumpPassphrase"`
+	NGINX          *NGINXSettings          `json:"nginx"`   // replaces the stored settings as a whole
+	Traefik        *TraefikSettings        `json:"traefik"` // replaces the stored settings as a whole
+	HAProxy        *HAProxySettingsRequest `json:"haproxy"` // replaces the stored settings as a whole
+	Logs           *LogSettings            `json:"logs"`    // replaces the stored settings as a whole
+	APIURL         *string                 `json:"apiUrl"`
+	APIToken       *string                 `json:"apiToken"`
+}
+
+// GuardOptions turns a config apply or reload into a guarded one: after the
+// reload the proxy is probed for GraceSeconds and the previous config is
+// restored if it turns unhealthy.
+type GuardOptions struct {
+	GraceSeconds int           `json:"graceSeconds"`
+	Checks       []HealthProbe `json:"checks"`
+}
+
+// HealthProbe is a user-defined HTTP check against the proxy. With no
+// ExpectStatus any response below 500 counts as healthy.
+type HealthProbe struct {
+	URL          string `json:"url" binding:"required"`
+	Host         string `json:"host,omitempty"`
+	ExpectStatus int    `json:"expectStatus,omitempty"`
+}
+
+// ProbeResult is the outcome of one probe in a guarded apply.
+type ProbeResult struct {
+	Name      string `json:"name"`
+	OK        bool   `json:"ok"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+// GuardResult reports how a guarded apply ended.
+type GuardResult struct {
+	Healthy    bool          `json:"healthy"`
+	RolledBack bool          `json:"rolledBack"`
+	Rounds     int           `json:"rounds"`
+	Probes     []ProbeResult `json:"probes"`
+	Error      string        `json:"error,omitempty"`
 }
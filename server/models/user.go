@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+type UserRole string
+
+const (
+	RoleAdmin    UserRole = "admin"
+	RoleOperator UserRole = "operator"
+	RoleViewer   UserRole = "viewer"
+)
+
+type User struct {
+	ID           string     `gorm:"primaryKey;type:text" json:"id"`
+	Name         string     `json:"name"`
+	Email        string     `gorm:"not null;uniqueIndex" json:"email"`
+	PasswordHash string     `gorm:"not null" json:"-"`
+	Role         UserRole   `gorm:"not null;default:viewer" json:"role"`
+	LastLogin    *time.Time `json:"lastLogin,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+
+	// Optional operator scope: when non-empty, the user may only manage
+	// servers (and their routes/config) carrying at least one of these tags.
+	ServerTagsJSON string   `gorm:"column:server_tags;default:'[]'" json:"-"`
+	ServerTags     []string `gorm:"-" json:"serverTags"`
+}
+
+// Session is a login session. ID is the SHA-256 of the secret half of the
+// session token; the token itself is never stored.
+type Session struct {
+	ID        string    `gorm:"primaryKey;type:text" json:"-"`
+	UserID    string    `gorm:"not null;index" json:"userId"`
+	IPAddress string    `json:"ipAddress"`
+	UserAgent string    `json:"userAgent"`
+	ExpiresAt time.Time `gorm:"index" json:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type LoginResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	User      User      `json:"user"`
+}
+
+type CreateUserRequest struct {
+	Name       string   `json:"name"`
+	Email      string   `json:"email" binding:"required"`
+	Password   string   `json:"password" binding:"required"`
+	Role       UserRole `json:"role" binding:"required"`
+	ServerTags []string `json:"serverTags"`
+}
+
+type UpdateUserRequest struct {
+	Name       *string   `json:"name"`
+	Password   *string   `json:"password"`
+	Role       *UserRole `json:"role"`
+	ServerTags []string  `json:"serverTags"`
+}
@@ -0,0 +1,274 @@
+package proxy
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anveesa/proxera/models"
+)
+
+var (
+	// combinedLogRe matches the NGINX combined format, which Traefik's
+	// common log format extends:
+	// 10.0.0.1 - - [10/Oct/2026:13:55:36 +0000] "GET /a HTTP/1.1" 200 512 "-" "curl/8.0"
+	combinedLogRe = regexp.MustCompile(`^(\S+) \S+ \S+ \[([^\]]+)\] "([^"]*)" (\d{3}) (\d+|-)(?: "[^"]*" "[^"]*")?(.*)$`)
+
+	// traefikCLFTailRe matches what Traefik appends to the combined format:
+	// the request count, router name, server URL and duration.
+	traefikCLFTailRe = regexp.MustCompile(`^\s*\d+ "([^"]*)" "([^"]*)" (\d+)ms`)
+
+	// logFieldRe matches key=value fields appended to a custom NGINX format,
+	// as in `rt=$request_time ua="$upstream_addr"`.
+	logFieldRe = regexp.MustCompile(`(\w+)=("[^"]*"|\S+)`)
+
+	// haproxyHTTPLogRe matches HAProxy's `option httplog` format, with or
+	// without a syslog prefix:
+	// 10.0.1.2:33317 [06/Feb/2026:12:14:14.655] http-in static/srv1 10/0/30/69/109 200 2750 - - ---- 1/1/1/1/0 0/0 "GET /i HTTP/1.1"
+	haproxyHTTPLogRe = regexp.MustCompile(`(\S+):\d+ \[([^\]]+)\] \S+ (\S+) -?\d+/-?\d+/-?\d+/-?\d+/\+?(-?\d+) (-?\d+) \+?(\d+) .*?"([^"]*)"`)
+)
+
+// accessLevel ranks an access log entry by its status.
+func accessLevel(status int) string {
+	switch {
+	case status >= 500:
+		return "error"
+	case status >= 400:
+		return "warn"
+	}
+	return "info"
+}
+
+func parseCombinedLog(line string) *models.LogEntry {
+	m := combinedLogRe.FindStringSubmatch(line)
+	if m == nil {
+		return nil
+	}
+	e := &models.LogEntry{ClientIP: m[1]}
+	e.Timestamp, _ = time.Parse("02/Jan/2006:15:04:05 -0700", m[2])
+	e.Method, e.Path = splitRequestLine(m[3])
+	e.Status, _ = strconv.Atoi(m[4])
+	e.Bytes, _ = strconv.ParseInt(m[5], 10, 64)
+
+	rest := m[6]
+	if t := traefikCLFTailRe.FindStringSubmatch(rest); t != nil {
+		if t[2] != "-" {
+			e.Upstream = t[2]
+		}
+		if ms, err := strconv.ParseFloat(t[3], 64); err == nil {
+			e.Duration = &ms
+		}
+		return e
+	}
+	for _, f := range logFieldRe.FindAllStringSubmatch(rest, -1) {
+		value := strings.Trim(f[2], `"`)
+		switch f[1] {
+		case "rt", "request_time":
+			if s, err := strconv.ParseFloat(value, 64); err == nil {
+				ms := s * 1000
+				e.Duration = &ms
+			}
+		case "ua", "upstream", "upstream_addr":
+			if value != "-" {
+				e.Upstream = value
+			}
+		case "host", "http_host":
+			e.Host = value
+		}
+	}
+	return e
+}
+
+// parseNGINXJSONLog reads a JSON access log written with
+// `log_format ... escape=json`, keyed by the nginx variable names.
+func parseNGINXJSONLog(line string) *models.LogEntry {
+	f, ok := jsonLogFields(line)
+	if !ok {
+		return nil
+	}
+	status, ok := f.num("status")
+	if !ok {
+		return nil
+	}
+	e := &models.LogEntry{
+		ClientIP: f.str("remote_addr", "client_ip"),
+		Method:   f.str("request_method", "method"),
+		Host:     f.str("host", "http_host", "server_name"),
+		Path:     f.str("request_uri", "uri"),
+		Status:   int(status),
+		Upstream: f.str("upstream_addr", "upstream"),
+	}
+	if e.Method == "" && e.Path == "" {
+		e.Method, e.Path = splitRequestLine(f.str("request"))
+	}
+	bytes, _ := f.num("body_bytes_sent", "bytes_sent")
+	e.Bytes = int64(bytes)
+	if s, ok := f.num("request_time"); ok {
+		ms := s * 1000
+		e.Duration = &ms
+	}
+	if ts := f.str("time_iso8601", "@timestamp", "time"); ts != "" {
+		e.Timestamp, _ = time.Parse(time.RFC3339, ts)
+	} else if ts := f.str("time_local"); ts != "" {
+		e.Timestamp, _ = time.Parse("02/Jan/2006:15:04:05 -0700", ts)
+	}
+	if e.Upstream == "-" {
+		e.Upstream = ""
+	}
+	return e
+}
+
+// parseCaddyJSONLog reads Caddy's access log, whose entries carry the
+// request as an object and the duration in seconds:
+// {"level":"info","ts":1760000000.1,"logger":"http.log.access","request":{...},"duration":0.004,"size":512,"status":200}
+func parseCaddyJSONLog(line string) *models.LogEntry {
+	f, ok := jsonLogFields(line)
+	if !ok {
+		return nil
+	}
+	if logger := f.str("logger"); logger != "" && !strings.HasPrefix(logger, "http.log.access") {
+		return nil
+	}
+	req, ok := f["request"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	status, ok := f.num("status")
+	if !ok {
+		return nil
+	}
+	r := logFields(req)
+	e := &models.LogEntry{
+		ClientIP: r.str("client_ip", "remote_ip"),
+		Method:   r.str("method"),
+		Host:     r.str("host"),
+		Path:     r.str("uri"),
+		Status:   int(status),
+	}
+	size, _ := f.num("size")
+	e.Bytes = int64(size)
+	if s, ok := f.num("duration"); ok {
+		ms := s * 1000
+		e.Duration = &ms
+	}
+	e.Timestamp = f.time("ts")
+	return e
+}
+
+// parseTraefikJSONLog reads Traefik's JSON access log. Durations are in
+// nanoseconds.
+func parseTraefikJSONLog(line string) *models.LogEntry {
+	f, ok := jsonLogFields(line)
+	if !ok {
+		return nil
+	}
+	status, ok := f.num("DownstreamStatus")
+	if !ok {
+		return nil
+	}
+	e := &models.LogEntry{
+		ClientIP: f.str("ClientHost"),
+		Method:   f.str("RequestMethod"),
+		Host:     f.str("RequestHost"),
+		Path:     f.str("RequestPath"),
+		Status:   int(status),
+		Upstream: f.str("ServiceAddr", "ServiceURL"),
+	}
+	size, _ := f.num("DownstreamContentSize")
+	e.Bytes = int64(size)
+	if ns, ok := f.num("Duration"); ok {
+		ms := ns / 1e6
+		e.Duration = &ms
+	}
+	e.Timestamp, _ = time.Parse(time.RFC3339Nano, f.str("StartUTC", "time"))
+	return e
+}
+
+// parseHAProxyHTTPLog reads HAProxy's HTTP log format. The accept date has
+// no zone, so it is read as local time. The duration is Ta, the total
+// active time; the upstream is backend/server.
+func parseHAProxyHTTPLog(line string) *models.LogEntry {
+	m := haproxyHTTPLogRe.FindStringSubmatch(line)
+	if m == nil {
+		return nil
+	}
+	e := &models.LogEntry{ClientIP: m[1], Upstream: m[3]}
+	e.Timestamp, _ = time.ParseInLocation("02/Jan/2006:15:04:05.000", m[2], time.Local)
+	if ms, err := strconv.ParseFloat(m[4], 64); err == nil && ms >= 0 {
+		e.Duration = &ms
+	}
+	if status, _ := strconv.Atoi(m[5]); status > 0 {
+		e.Status = status
+	}
+	e.Bytes, _ = strconv.ParseInt(m[6], 10, 64)
+	e.Method, e.Path = splitRequestLine(m[7])
+	return e
+}
+
+// splitRequestLine splits "GET /path HTTP/1.1".
+func splitRequestLine(request string) (method, path string) {
+	parts := strings.Fields(request)
+	if len(parts) < 2 {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
+
+// logFields is a decoded JSON log line.
+type logFields map[string]any
+
+func jsonLogFields(line string) (logFields, bool) {
+	if !strings.HasPrefix(line, "{") {
+		return nil, false
+	}
+	var f logFields
+	if err := json.Unmarshal([]byte(line), &f); err != nil {
+		return nil, false
+	}
+	return f, true
+}
+
+// str returns the first of keys that holds a non-empty string.
+func (f logFields) str(keys ...string) string {
+	for _, k := range keys {
+		if s, ok := f[k].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// time reads the first of keys that holds a Unix time in seconds or an
+// RFC 3339 string.
+func (f logFields) time(keys ...string) time.Time {
+	for _, k := range keys {
+		switch v := f[k].(type) {
+		case float64:
+			sec, frac := int64(v), v-float64(int64(v))
+			return time.Unix(sec, int64(frac*1e9)).UTC()
+		case string:
+			if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// num returns the first of keys that holds a number, or a string that
+// parses as one (nginx writes every variable as a string).
+func (f logFields) num(keys ...string) (float64, bool) {
+	for _, k := range keys {
+		switch v := f[k].(type) {
+		case float64:
+			return v, true
+		case string:
+			if n, err := strconv.ParseFloat(v, 64); err == nil {
+				return n, true
+			}
+		}
+	}
+	return 0, false
+}
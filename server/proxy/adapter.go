@@ -2,6 +2,7 @@ package proxy
 
 import (
 	"context"
+	"errors"
 	"io"
 
 	"github.com/anveesa/proxera/models"
@@ -27,6 +28,30 @@ type ProxyAdapter interface {
 	GetStatus(ctx context.Context) (string, error)
 }
 
+// ConfigFilesWriter is implemented by adapters whose configuration spans
+// several files. PutConfigFiles replaces the given files, leaving the rest
+// of the tree alone, validates the whole tree and reverts the files if it
+// is invalid.
+type ConfigFilesWriter interface {
+	PutConfigFiles(ctx context.Context, files []models.ConfigFile) (*models.ConfigValidation, error)
+}
+
+// ConfigChangesWriter is implemented by adapters whose configuration API
+// edits individual objects. ApplyConfigChanges applies all changes or none.
+type ConfigChangesWriter interface {
+	ApplyConfigChanges(ctx context.Context, changes []models.ConfigChange) (*models.ConfigValidation, error)
+}
+
+// ServerStateController is implemented by adapters that can take a
+// backend server in and out of rotation, or reweight it, without a reload.
+type ServerStateController interface {
+	SetServerState(ctx context.Context, backend, server string, change models.ServerStateChange) (*models.BackendServerState, error)
+}
+
+// ErrNotFound is matched (with errors.Is) by adapter errors for objects the
+// proxy does not know.
+var ErrNotFound = errors.New("not found")
+
 // ErrNotSupported is returned when an operation is not supported by the adapter.
 type ErrNotSupported struct {
 	Op string
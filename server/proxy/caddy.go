@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"strings"
 	"time"
@@ -19,20 +20,26 @@ type CaddyAdapter struct {
 	serverName string
 	apiURL     string
 	httpClient *http.Client
+	logs       *logSource // nil when no log source is set
 }
 
-func NewCaddyAdapter(serverID, serverName, apiURL string) *CaddyAdapter {
+func NewCaddyAdapter(serverID, serverName, apiURL string, logs *logSource) *CaddyAdapter {
 	return &CaddyAdapter{
 		serverID:   serverID,
 		serverName: serverName,
 		apiURL:     strings.TrimRight(apiURL, "/"),
 		httpClient: &http.Client{Timeout: 10 * time.Second},
+		logs:       logs,
 	}
 }
 
 func (a *CaddyAdapter) Type() string { return "caddy" }
 
 func (a *CaddyAdapter) doRequest(ctx context.Context, method, path string, body []byte) ([]byte, int, error) {
+	return a.doRequestWithHeaders(ctx, method, path, body, nil)
+}
+
+func (a *CaddyAdapter) doRequestWithHeaders(ctx context.Context, method, path string, body []byte, headers map[string]string) ([]byte, int, error) {
 	var bodyReader io.Reader
 	if body != nil {
 		bodyReader = bytes.NewReader(body)
@@ -44,6 +51,9 @@ func (a *CaddyAdapter) doRequest(ctx context.Context, method, path string, body
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
 	resp, err := a.httpClient.Do(req)
 	if err != nil {
 		return nil, 0, err
@@ -65,11 +75,100 @@ func (a *CaddyAdapter) Ping(ctx context.Context) (int64, error) {
 	return time.Since(start).Milliseconds(), nil
 }
 
+// GetMetrics scrapes the admin API's Prometheus endpoint; see caddyMetrics.
 func (a *CaddyAdapter) GetMetrics(ctx context.Context) (*models.ServerMetrics, error) {
-	return &models.ServerMetrics{
-		ServerID:  a.serverID,
-		Timestamp: time.Now(),
-	}, nil
+	body, status, err := a.doRequest(ctx, "GET", "/metrics", nil)
+	if err != nil {
+		return nil, err
+	}
+	if status != 200 {
+		return nil, fmt.Errorf("caddy metrics returned %d", status)
+	}
+	pm, err := parsePrometheus(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("parse caddy metrics: %w", err)
+	}
+	return caddyMetrics(a.serverID, pm), nil
+}
+
+// caddyMetrics derives server metrics from Caddy's HTTP metrics. Rates,
+// the error rate and latency percentiles cover the time since the previous
+// scrape; on the first one there are no rates and the others cover
+// Caddy's lifetime. A handler error is normally also a 5xx response, so
+// errors are the larger of the two counts rather than their sum. Only one
+// handler per Caddy server is counted; see caddyHandlerFilter.
+func caddyMetrics(serverID string, pm promMetrics) *models.ServerMetrics {
+	const duration = "caddy_http_request_duration_seconds"
+	requests := "caddy_http_requests_total"
+	if _, ok := pm[requests]; !ok {
+		requests = duration + "_count"
+	}
+	counted := caddyHandlerFilter(pm, requests)
+	serverError := func(l map[string]string) bool { return counted(l) && strings.HasPrefix(l["code"], "5") }
+
+	counters := map[string]float64{
+		"requests": pm.sum(requests, counted),
+		"errors":   pm.sum("caddy_http_request_errors_total", counted),
+		"5xx":      pm.sum(duration+"_count", serverError),
+		"bytesIn":  pm.sum("caddy_http_request_size_bytes_sum", counted),
+		"bytesOut": pm.sum("caddy_http_response_size_bytes_sum", counted),
+	}
+	addBuckets(counters, "duration:", pm.buckets(duration, counted))
+
+	m := &models.ServerMetrics{
+		ServerID:          serverID,
+		Timestamp:         time.Now(),
+		ActiveConnections: int(pm.sum("caddy_http_requests_in_flight", counted)),
+	}
+	window := counters
+	deltas, seconds, ok := counterDeltas(serverID, counters)
+	m.Cumulative = !ok
+	if ok {
+		window = deltas
+		m.RequestsPerSec = deltas["requests"] / seconds
+		m.NetworkIn = deltas["bytesIn"] / seconds
+		m.NetworkOut = deltas["bytesOut"] / seconds
+	}
+	if window["requests"] > 0 {
+		m.ErrorRate = math.Max(window["errors"], window["5xx"]) / window["requests"] * 100
+	}
+	setLatencyPercentiles(m, bucketsFrom(window, "duration:"))
+	return m
+}
+
+// caddyHandlerFilter keeps the series of one handler per Caddy server.
+// Caddy labels its HTTP metrics with the handler that recorded them, and a
+// request passing through nested handlers (a subroute, then reverse_proxy)
+// is recorded by each, so summing across handlers counts it several times.
+// reverse_proxy is used where a server has it, otherwise the handler that
+// saw the most requests, which is the outermost one.
+func caddyHandlerFilter(pm promMetrics, requests string) func(labels map[string]string) bool {
+	seen := map[string]map[string]float64{}
+	for _, s := range pm[requests] {
+		if seen[s.labels["server"]] == nil {
+			seen[s.labels["server"]] = map[string]float64{}
+		}
+		seen[s.labels["server"]][s.labels["handler"]] += s.value
+	}
+	chosen := map[string]string{}
+	for server, handlers := range seen {
+		if _, ok := handlers["reverse_proxy"]; ok {
+			chosen[server] = "reverse_proxy"
+			continue
+		}
+		keys := sortedKeys(handlers)
+		best := keys[0]
+		for _, h := range keys[1:] {
+			if handlers[h] > handlers[best] {
+				best = h
+			}
+		}
+		chosen[server] = best
+	}
+	return func(labels map[string]string) bool {
+		h, ok := chosen[labels["server"]]
+		return !ok || labels["handler"] == h
+	}
 }
 
 func (a *CaddyAdapter) GetConfig(ctx context.Context) (*models.ProxyConfig, error) {
@@ -116,8 +215,19 @@ func (a *CaddyAdapter) PutConfig(ctx context.Context, content string) (*models.C
 	return &models.ConfigValidation{IsValid: true}, nil
 }
 
+// Reload re-submits the running config; Cache-Control: must-revalidate
+// makes Caddy reload it even though it is unchanged.
 func (a *CaddyAdapter) Reload(ctx context.Context) error {
-	_, status, err := a.doRequest(ctx, "POST", "/load", nil)
+	current, status, err := a.doRequest(ctx, "GET", "/config/", nil)
+	if err != nil {
+		return err
+	}
+	if status != 200 {
+		return fmt.Errorf("fetch config returned %d", status)
+	}
+	_, status, err = a.doRequestWithHeaders(ctx, "POST", "/load", current, map[string]string{
+		"Cache-Control": "must-revalidate",
+	})
 	if err != nil {
 		return err
 	}
@@ -127,8 +237,8 @@ func (a *CaddyAdapter) Reload(ctx context.Context) error {
 	return nil
 }
 
-func (a *CaddyAdapter) TailLogs(_ context.Context) (io.ReadCloser, error) {
-	return nil, &ErrNotSupported{Op: "TailLogs"}
+func (a *CaddyAdapter) TailLogs(ctx context.Context) (io.ReadCloser, error) {
+	return tailLogs(ctx, a.logs)
 }
 
 func (a *CaddyAdapter) GetStatus(ctx context.Context) (string, error) {
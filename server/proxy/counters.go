@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+)
+
+// minCounterWindow keeps a burst of polls (collector, live view, stream)
+// from shrinking the window rates are computed over to nothing.
+const minCounterWindow = time.Second
+
+// counterSample is one reading of a server's cumulative counters.
+type counterSample struct {
+	at     time.Time
+	values map[string]float64
+}
+
+// counterSamples holds each server's previous counter reading. Adapters
+// are built per request, so it lives at package level, keyed by server ID.
+var counterSamples = struct {
+	sync.Mutex
+	m map[string]counterSample
+}{m: map[string]counterSample{}}
+
+// counterDeltas returns how much each counter grew since the previous
+// reading for serverID, and the seconds in between; ok is false when there
+// is no usable previous reading. A counter that went backwards was reset
+// by a restart, so its current value is its growth. The new reading
+// replaces the stored one once minCounterWindow has passed.
+func counterDeltas(serverID string, values map[string]float64) (deltas map[string]float64, seconds float64, ok bool) {
+	now := time.Now()
+	counterSamples.Lock()
+	defer counterSamples.Unlock()
+
+	prev, found := counterSamples.m[serverID]
+	elapsed := now.Sub(prev.at)
+	if !found || elapsed >= minCounterWindow {
+		counterSamples.m[serverID] = counterSample{at: now, values: values}
+	}
+	if !found || elapsed <= 0 {
+		return nil, 0, false
+	}
+
+	deltas = make(map[string]float64, len(values))
+	for k, v := range values {
+		old, seen := prev.values[k]
+		switch {
+		case !seen:
+			continue
+		case v >= old:
+			deltas[k] = v - old
+		default:
+			deltas[k] = v
+		}
+	}
+	return deltas, elapsed.Seconds(), true
+}
+
+// ForgetCounters drops the stored counter reading for a server, for when
+// it is deleted.
+func ForgetCounters(serverID string) {
+	counterSamples.Lock()
+	defer counterSamples.Unlock()
+	delete(counterSamples.m, serverID)
+}
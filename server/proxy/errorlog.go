@@ -0,0 +1,151 @@
+package proxy
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/anveesa/proxera/models"
+)
+
+var (
+	// nginxErrorLogRe matches an NGINX error log line:
+	// 2026/10/10 13:55:36 [error] 1234#1234: *5 connect() failed (111: Connection refused) while connecting to upstream, client: 10.0.0.1, ...
+	nginxErrorLogRe = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}) \[(\w+)\] \d+#\d+: (?:\*\d+ )?(.*)$`)
+
+	// nginxErrorContextRe matches the request context NGINX appends to an
+	// error message: `, client: 10.0.0.1, request: "GET / HTTP/1.1"`.
+	nginxErrorContextRe = regexp.MustCompile(`, (client|server|request|upstream|host): ("[^"]*"|[^,]*)`)
+
+	// haproxyErrorLogRe matches HAProxy's own messages, as printed on
+	// stderr: [WARNING]  (1) : Server app/web1 is DOWN, reason: Layer4 timeout
+	haproxyErrorLogRe = regexp.MustCompile(`\[(NOTICE|WARNING|ALERT|EMERG|ERR|INFO|DEBUG)\]\s+(?:\(\d+\)\s*:\s*)?(.*)$`)
+
+	// haproxyServerStateRe matches a backend server changing state, which
+	// HAProxy also sends to syslog without a level tag.
+	haproxyServerStateRe = regexp.MustCompile(`(?:Server|backup Server) (\S+/\S+) is (UP|DOWN)`)
+
+	// syslogTimeRe matches the timestamp that starts a syslog line.
+	syslogTimeRe = regexp.MustCompile(`^([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}) `)
+
+	// traefikConsoleLogRe matches Traefik v3's console log format:
+	// 2026-10-10T13:55:36Z ERR Error while starting server error="..."
+	traefikConsoleLogRe = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}T\S+) (TRC|DBG|INF|WRN|ERR|FTL|PNC) `)
+)
+
+// parseNGINXErrorLog reads NGINX's error log. Its timestamps have no zone,
+// so they are read as local time.
+func parseNGINXErrorLog(line string) *models.LogEntry {
+	m := nginxErrorLogRe.FindStringSubmatch(line)
+	if m == nil {
+		return nil
+	}
+	e := &models.LogEntry{Level: logLevel(m[2])}
+	e.Timestamp, _ = time.ParseInLocation("2006/01/02 15:04:05", m[1], time.Local)
+	for _, f := range nginxErrorContextRe.FindAllStringSubmatch(m[3], -1) {
+		value := strings.Trim(f[2], `"`)
+		switch f[1] {
+		case "client":
+			e.ClientIP = value
+		case "request":
+			e.Method, e.Path = splitRequestLine(value)
+		case "upstream":
+			e.Upstream = value
+		case "host":
+			e.Host = value
+		}
+	}
+	return e
+}
+
+// parseHAProxyErrorLog reads HAProxy's own messages: tagged with a level
+// on stderr, or server state changes sent to syslog.
+func parseHAProxyErrorLog(line string) *models.LogEntry {
+	e := &models.LogEntry{}
+	if m := haproxyErrorLogRe.FindStringSubmatch(line); m != nil {
+		e.Level = logLevel(m[1])
+	}
+	if m := haproxyServerStateRe.FindStringSubmatch(line); m != nil {
+		e.Upstream = m[1]
+		if e.Level == "" {
+			e.Level = "info"
+			if m[2] == "DOWN" {
+				e.Level = "warn"
+			}
+		}
+	}
+	if e.Level == "" {
+		return nil
+	}
+	if m := syslogTimeRe.FindStringSubmatch(line); m != nil {
+		if t, err := time.ParseInLocation(time.Stamp, m[1], time.Local); err == nil {
+			// Syslog leaves out the year.
+			e.Timestamp = t.AddDate(time.Now().Year(), 0, 0)
+		}
+	}
+	return e
+}
+
+// parseJSONErrorLog reads a JSON log entry with a level, as Caddy and
+// Traefik write when set to JSON. Caddy's HTTP errors also carry the
+// request.
+func parseJSONErrorLog(line string) *models.LogEntry {
+	f, ok := jsonLogFields(line)
+	if !ok {
+		return nil
+	}
+	level := f.str("level")
+	if level == "" || f.str("msg", "message", "error") == "" {
+		return nil
+	}
+	e := &models.LogEntry{
+		Timestamp: f.time("ts", "time"),
+		Level:     logLevel(level),
+		Upstream:  f.str("upstream"),
+	}
+	if req, ok := f["request"].(map[string]any); ok {
+		r := logFields(req)
+		e.ClientIP = r.str("client_ip", "remote_ip")
+		e.Method = r.str("method")
+		e.Host = r.str("host")
+		e.Path = r.str("uri")
+	}
+	if status, ok := f.num("status"); ok {
+		e.Status = int(status)
+	}
+	return e
+}
+
+// parseTraefikErrorLog reads Traefik's text logs: v2's
+// `time="..." level=error msg="..."` and v3's console format.
+func parseTraefikErrorLog(line string) *models.LogEntry {
+	if m := traefikConsoleLogRe.FindStringSubmatch(line); m != nil {
+		e := &models.LogEntry{Level: logLevel(m[2])}
+		e.Timestamp, _ = time.Parse(time.RFC3339Nano, m[1])
+		return e
+	}
+	fields := map[string]string{}
+	for _, f := range logFieldRe.FindAllStringSubmatch(line, -1) {
+		fields[f[1]] = strings.Trim(f[2], `"`)
+	}
+	if fields["level"] == "" || fields["msg"] == "" {
+		return nil
+	}
+	e := &models.LogEntry{Level: logLevel(fields["level"])}
+	e.Timestamp, _ = time.Parse(time.RFC3339Nano, fields["time"])
+	return e
+}
+
+// logLevel maps the level names and abbreviations proxies log with to
+// debug, info, warn or error.
+func logLevel(level string) string {
+	switch strings.ToLower(level) {
+	case "debug", "trace", "dbg", "trc":
+		return "debug"
+	case "warn", "warning", "wrn":
+		return "warn"
+	case "error", "err", "crit", "critical", "alert", "emerg", "fatal", "ftl", "panic", "pnc":
+		return "error"
+	}
+	return "info"
+}
@@ -2,11 +2,9 @@ package proxy
 
 import (
 	"context"
-	"encoding/csv"
 	"fmt"
 	"io"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
@@ -14,34 +12,59 @@ import (
 )
 
 // HAProxyAdapter connects to HAProxy via its Stats / Data Plane API.
+// Stats are read from apiURL; configuration goes through the Data Plane
+// API, which validates every write and reloads HAProxy on commit.
 type HAProxyAdapter struct {
-	serverID   string
-	serverName string
-	apiURL     string
-	apiToken   string
-	httpClient *http.Client
+	serverID     string
+	serverName   string
+	apiURL       string
+	apiToken     string
+	dataPlaneURL string
+	apiVersion   string // "v2" or "v3"; detected on first use when empty
+	username     string
+	password     string
+	httpClient   *http.Client
+	socket       *haproxySocket // nil when no stats socket is set
+	logs         *logSource     // nil when no log source is set
 }
 
-func NewHAProxyAdapter(serverID, serverName, apiURL, apiToken string) *HAProxyAdapter {
+func NewHAProxyAdapter(serverID, serverName, apiURL, apiToken string, settings models.HAProxySettings, password string, socket *haproxySocket, logs *logSource) *HAProxyAdapter {
+	dataPlaneURL := settings.DataPlaneURL
+	if dataPlaneURL == "" {
+		dataPlaneURL = apiURL
+	}
 	return &HAProxyAdapter{
-		serverID:   serverID,
-		serverName: serverName,
-		apiURL:     strings.TrimRight(apiURL, "/"),
-		apiToken:   apiToken,
-		httpClient: &http.Client{Timeout: 10 * time.Second},
+		serverID:     serverID,
+		serverName:   serverName,
+		apiURL:       strings.TrimRight(apiURL, "/"),
+		apiToken:     apiToken,
+		dataPlaneURL: strings.TrimRight(dataPlaneURL, "/"),
+		apiVersion:   settings.APIVersion,
+		username:     settings.Username,
+		password:     password,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+		socket:       socket,
+		logs:         logs,
 	}
 }
 
 func (a *HAProxyAdapter) Type() string { return "haproxy" }
 
+// authorize adds basic auth if a username is set, else the bearer token.
+func (a *HAProxyAdapter) authorize(req *http.Request) {
+	if a.username != "" {
+		req.SetBasicAuth(a.username, a.password)
+	} else if a.apiToken != "" {
+		req.Header.Set("Authorization", "Bearer "+a.apiToken)
+	}
+}
+
 func (a *HAProxyAdapter) doGet(ctx context.Context, path string) ([]byte, int, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", a.apiURL+path, nil)
 	if err != nil {
 		return nil, 0, err
 	}
-	if a.apiToken != "" {
-		req.Header.Set("Authorization", "Bearer "+a.apiToken)
-	}
+	a.authorize(req)
 	resp, err := a.httpClient.Do(req)
 	if err != nil {
 		return nil, 0, err
@@ -63,81 +86,74 @@ func (a *HAProxyAdapter) Ping(ctx context.Context) (int64, error) {
 	return time.Since(start).Milliseconds(), nil
 }
 
+// GetMetrics reads the stats CSV; see haproxyMetrics.
 func (a *HAProxyAdapter) GetMetrics(ctx context.Context) (*models.ServerMetrics, error) {
-	body, _, err := a.doGet(ctx, "/stats;csv;norefresh")
+	body, status, err := a.doGet(ctx, "/stats;csv;norefresh")
 	if err != nil {
 		return nil, err
 	}
-
-	m := &models.ServerMetrics{
-		ServerID:  a.serverID,
-		Timestamp: time.Now(),
-	}
-
-	r := csv.NewReader(strings.NewReader(string(body)))
-	r.Comment = '#'
-	records, err := r.ReadAll()
-	if err != nil || len(records) < 2 {
-		return m, nil
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("haproxy stats returned %d", status)
 	}
-
-	headers := records[0]
-	colIdx := make(map[string]int, len(headers))
-	for i, h := range headers {
-		colIdx[strings.TrimSpace(h)] = i
-	}
-
-	var totalConns, totalReq int64
-	for _, row := range records[1:] {
-		if idx, ok := colIdx["scur"]; ok && idx < len(row) {
-			v, _ := strconv.ParseInt(strings.TrimSpace(row[idx]), 10, 64)
-			totalConns += v
-		}
-		if idx, ok := colIdx["req_tot"]; ok && idx < len(row) {
-			v, _ := strconv.ParseInt(strings.TrimSpace(row[idx]), 10, 64)
-			totalReq += v
-		}
+	rows, err := parseHAProxyStats(body)
+	if err != nil {
+		return nil, err
 	}
-
-	m.ActiveConnections = int(totalConns)
-	m.RequestsPerSec = float64(totalReq)
-	return m, nil
+	return haproxyMetrics(a.serverID, rows), nil
 }
 
+// GetConfig returns haproxy.cfg as the Data Plane API has it.
 func (a *HAProxyAdapter) GetConfig(ctx context.Context) (*models.ProxyConfig, error) {
-	body, _, err := a.doGet(ctx, "/v2/services/haproxy/configuration/global")
+	content, _, err := a.rawConfig(ctx)
 	if err != nil {
-		return &models.ProxyConfig{
-			ServerID:     a.serverID,
-			ServerName:   a.serverName,
-			ProxyType:    models.ProxyHAProxy,
-			Content:      "# HAProxy config (Data Plane API not available)",
-			Format:       "haproxy",
-			LastModified: time.Now().Format(time.RFC3339),
-			IsValid:      true,
-		}, nil
+		return nil, fmt.Errorf("data plane API: %w", err)
 	}
 	return &models.ProxyConfig{
 		ServerID:     a.serverID,
 		ServerName:   a.serverName,
 		ProxyType:    models.ProxyHAProxy,
-		Content:      string(body),
+		Content:      content,
 		Format:       "haproxy",
 		LastModified: time.Now().Format(time.RFC3339),
 		IsValid:      true,
 	}, nil
 }
 
-func (a *HAProxyAdapter) PutConfig(_ context.Context, _ string) (*models.ConfigValidation, error) {
-	return nil, &ErrNotSupported{Op: "PutConfig"}
+// PutConfig replaces haproxy.cfg at the current configuration version,
+// after the Data Plane API has validated it. HAProxy is not reloaded.
+func (a *HAProxyAdapter) PutConfig(ctx context.Context, content string) (*models.ConfigValidation, error) {
+	version, err := a.configVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return a.postRaw(ctx, content, version, false)
 }
 
-func (a *HAProxyAdapter) Reload(_ context.Context) error {
-	return &ErrNotSupported{Op: "Reload"}
+// ApplyConfigChanges implements ConfigChangesWriter. Committing the
+// transaction reloads HAProxy.
+func (a *HAProxyAdapter) ApplyConfigChanges(ctx context.Context, changes []models.ConfigChange) (*models.ConfigValidation, error) {
+	return a.applyChanges(ctx, changes)
+}
+
+// Reload writes the current configuration back with force_reload; the
+// Data Plane API has no separate reload call.
+func (a *HAProxyAdapter) Reload(ctx context.Context) error {
+	content, version, err := a.rawConfig(ctx)
+	if err != nil {
+		return err
+	}
+	v, err := a.postRaw(ctx, content, version, true)
+	if err != nil {
+		return err
+	}
+	if !v.IsValid {
+		return fmt.Errorf("reload failed: %s", strings.Join(v.Errors, "; "))
+	}
+	return nil
 }
 
-func (a *HAProxyAdapter) TailLogs(_ context.Context) (io.ReadCloser, error) {
-	return nil, &ErrNotSupported{Op: "TailLogs"}
+func (a *HAProxyAdapter) TailLogs(ctx context.Context) (io.ReadCloser, error) {
+	return tailLogs(ctx, a.logs)
 }
 
 func (a *HAProxyAdapter) GetStatus(ctx context.Context) (string, error) {
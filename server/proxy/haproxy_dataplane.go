@@ -0,0 +1,318 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anveesa/proxera/models"
+)
+
+const (
+	// How long a reload triggered by a Data Plane API commit may take.
+	dataPlaneReloadTimeout  = 30 * time.Second
+	dataPlaneReloadInterval = 500 * time.Millisecond
+)
+
+// dataPlaneResponse is a Data Plane API reply.
+type dataPlaneResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// dataPlaneError is a non-2xx Data Plane API reply. Replies rejecting the
+// submitted configuration count as validation failures; anything else
+// (auth, unavailability) is an ordinary error.
+type dataPlaneError struct {
+	Status  int
+	Message string
+}
+
+func (e *dataPlaneError) Error() string {
+	return fmt.Sprintf("data plane API returned %d: %s", e.Status, e.Message)
+}
+
+// Is lets a 404 match ErrNotFound.
+func (e *dataPlaneError) Is(target error) bool {
+	return target == ErrNotFound && e.Status == http.StatusNotFound
+}
+
+func (e *dataPlaneError) validation() bool {
+	switch e.Status {
+	case http.StatusBadRequest, http.StatusConflict, http.StatusNotAcceptable, http.StatusUnprocessableEntity:
+		return true
+	}
+	return false
+}
+
+// lines splits the message into the individual problems HAProxy reported.
+func (e *dataPlaneError) lines() []string {
+	var out []string
+	for _, l := range strings.Split(e.Message, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			out = append(out, l)
+		}
+	}
+	if e.Status == http.StatusConflict {
+		out = append(out, "the configuration changed on the server in the meantime; fetch it again and retry")
+	}
+	return out
+}
+
+// dataPlane sends a request to the Data Plane API below
+// /<version>/services/haproxy and turns error replies into *dataPlaneError.
+func (a *HAProxyAdapter) dataPlane(ctx context.Context, method, path string, query url.Values, contentType string, body []byte) (*dataPlaneResponse, error) {
+	version, err := a.dataPlaneVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return a.dataPlaneAt(ctx, version, method, path, query, contentType, body)
+}
+
+func (a *HAProxyAdapter) dataPlaneAt(ctx context.Context, version, method, path string, query url.Values, contentType string, body []byte) (*dataPlaneResponse, error) {
+	u := a.dataPlaneURL + "/" + version + "/services/haproxy" + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var rd io.Reader
+	if body != nil {
+		rd = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, rd)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	a.authorize(req)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(b, &e) != nil || e.Message == "" {
+			e.Message = strings.TrimSpace(string(b))
+		}
+		return nil, &dataPlaneError{Status: resp.StatusCode, Message: e.Message}
+	}
+	return &dataPlaneResponse{status: resp.StatusCode, header: resp.Header, body: b}, nil
+}
+
+// dataPlaneVersion returns the configured API version, or detects it once:
+// a v2-only API answers 404 to v3 paths.
+func (a *HAProxyAdapter) dataPlaneVersion(ctx context.Context) (string, error) {
+	if a.apiVersion != "" {
+		return a.apiVersion, nil
+	}
+	_, err := a.dataPlaneAt(ctx, "v3", http.MethodGet, "/configuration/version", nil, "", nil)
+	if err == nil {
+		a.apiVersion = "v3"
+		return a.apiVersion, nil
+	}
+	if e, ok := err.(*dataPlaneError); !ok || e.Status != http.StatusNotFound {
+		return "", err
+	}
+	a.apiVersion = "v2"
+	return a.apiVersion, nil
+}
+
+// configVersion returns the configuration version every write must name.
+func (a *HAProxyAdapter) configVersion(ctx context.Context) (int64, error) {
+	resp, err := a.dataPlane(ctx, http.MethodGet, "/configuration/version", nil, "", nil)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(resp.body)), 10, 64)
+}
+
+// rawConfig returns haproxy.cfg and its version. v2 wraps the file in JSON;
+// v3 returns it as text with the version in a header.
+func (a *HAProxyAdapter) rawConfig(ctx context.Context) (string, int64, error) {
+	resp, err := a.dataPlane(ctx, http.MethodGet, "/configuration/raw", nil, "", nil)
+	if err != nil {
+		return "", 0, err
+	}
+	if strings.HasPrefix(resp.header.Get("Content-Type"), "application/json") {
+		var raw struct {
+			Version int64  `json:"_version"`
+			Data    string `json:"data"`
+		}
+		if err := json.Unmarshal(resp.body, &raw); err != nil {
+			return "", 0, fmt.Errorf("decode raw configuration: %w", err)
+		}
+		return raw.Data, raw.Version, nil
+	}
+	version, _ := strconv.ParseInt(resp.header.Get("Configuration-Version"), 10, 64)
+	return string(resp.body), version, nil
+}
+
+// postRaw replaces haproxy.cfg, which the Data Plane API validates with
+// `haproxy -c` before writing. With reload it also reloads HAProxy, even
+// if the file is unchanged, and waits for that; without, it only writes.
+func (a *HAProxyAdapter) postRaw(ctx context.Context, content string, version int64, reload bool) (*models.ConfigValidation, error) {
+	q := url.Values{"version": {strconv.FormatInt(version, 10)}}
+	if reload {
+		q.Set("force_reload", "true")
+	} else {
+		q.Set("skip_reload", "true")
+	}
+	resp, err := a.dataPlane(ctx, http.MethodPost, "/configuration/raw", q, "text/plain", []byte(content))
+	if err != nil {
+		return validationFromDataPlane(err)
+	}
+	return a.awaitReload(ctx, resp)
+}
+
+// applyChanges makes object-level edits in one transaction, so they are
+// validated and reloaded together or not at all.
+func (a *HAProxyAdapter) applyChanges(ctx context.Context, changes []models.ConfigChange) (*models.ConfigValidation, error) {
+	version, err := a.configVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.dataPlane(ctx, http.MethodPost, "/transactions",
+		url.Values{"version": {strconv.FormatInt(version, 10)}}, "", nil)
+	if err != nil {
+		return validationFromDataPlane(err)
+	}
+	var tx struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(resp.body, &tx); err != nil || tx.ID == "" {
+		return nil, fmt.Errorf("start transaction: unexpected reply %s", resp.body)
+	}
+	abort := func() {
+		a.dataPlane(context.WithoutCancel(ctx), http.MethodDelete, "/transactions/"+url.PathEscape(tx.ID), nil, "", nil) //nolint:errcheck
+	}
+
+	for _, ch := range changes {
+		method, path, query, body, err := a.changeRequest(ch)
+		if err != nil {
+			abort()
+			return &models.ConfigValidation{IsValid: false, Errors: []string{err.Error()}}, nil
+		}
+		query.Set("transaction_id", tx.ID)
+		if _, err := a.dataPlane(ctx, method, path, query, "application/json", body); err != nil {
+			abort()
+			v, err := validationFromDataPlane(err)
+			if v != nil {
+				for i := range v.Errors {
+					v.Errors[i] = fmt.Sprintf("%s %s %q: %s", ch.Op, ch.Kind, ch.Name, v.Errors[i])
+				}
+			}
+			return v, err
+		}
+	}
+
+	resp, err = a.dataPlane(ctx, http.MethodPut, "/transactions/"+url.PathEscape(tx.ID), nil, "", nil)
+	if err != nil {
+		abort()
+		return validationFromDataPlane(err)
+	}
+	return a.awaitReload(ctx, resp)
+}
+
+// changeRequest maps a change onto its Data Plane API endpoint. Servers
+// live under their backend in v3 and take it as a query parameter in v2.
+func (a *HAProxyAdapter) changeRequest(ch models.ConfigChange) (method, path string, query url.Values, body []byte, err error) {
+	query = url.Values{}
+	var collection string
+	switch ch.Kind {
+	case "frontend", "backend":
+		collection = "/configuration/" + ch.Kind + "s"
+	case "server":
+		if ch.Parent == "" {
+			return "", "", nil, nil, fmt.Errorf("server %q: parent backend is required", ch.Name)
+		}
+		if a.apiVersion == "v2" {
+			collection = "/configuration/servers"
+			query.Set("backend", ch.Parent)
+		} else {
+			collection = "/configuration/backends/" + url.PathEscape(ch.Parent) + "/servers"
+		}
+	default:
+		return "", "", nil, nil, fmt.Errorf("unsupported kind %q", ch.Kind)
+	}
+
+	if ch.Op != "delete" {
+		data := make(map[string]any, len(ch.Data)+1)
+		for k, v := range ch.Data {
+			data[k] = v
+		}
+		data["name"] = ch.Name
+		if body, err = json.Marshal(data); err != nil {
+			return "", "", nil, nil, err
+		}
+	}
+	switch ch.Op {
+	case "create":
+		return http.MethodPost, collection, query, body, nil
+	case "replace":
+		return http.MethodPut, collection + "/" + url.PathEscape(ch.Name), query, body, nil
+	case "delete":
+		return http.MethodDelete, collection + "/" + url.PathEscape(ch.Name), query, nil, nil
+	}
+	return "", "", nil, nil, fmt.Errorf("unsupported op %q", ch.Op)
+}
+
+// awaitReload follows the reload a write scheduled (202 with a Reload-ID)
+// and reports a failed reload as invalid. Writes that needed no reload
+// come back 200 or 201.
+func (a *HAProxyAdapter) awaitReload(ctx context.Context, resp *dataPlaneResponse) (*models.ConfigValidation, error) {
+	id := resp.header.Get("Reload-ID")
+	if resp.status != http.StatusAccepted || id == "" {
+		return &models.ConfigValidation{IsValid: true}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dataPlaneReloadTimeout)
+	defer cancel()
+	for {
+		r, err := a.dataPlane(ctx, http.MethodGet, "/reloads/"+url.PathEscape(id), nil, "", nil)
+		if err == nil {
+			var reload struct {
+				Status   string `json:"status"`
+				Response string `json:"response"`
+			}
+			json.Unmarshal(r.body, &reload) //nolint:errcheck
+			switch reload.Status {
+			case "succeeded":
+				return &models.ConfigValidation{IsValid: true}, nil
+			case "failed":
+				errs := (&dataPlaneError{Message: reload.Response}).lines()
+				return &models.ConfigValidation{IsValid: false, Errors: append([]string{"reload " + id + " failed"}, errs...)}, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("reload %s did not finish within %s", id, dataPlaneReloadTimeout)
+		case <-time.After(dataPlaneReloadInterval):
+		}
+	}
+}
+
+// validationFromDataPlane turns a rejected write into a validation result
+// and passes other errors through.
+func validationFromDataPlane(err error) (*models.ConfigValidation, error) {
+	if e, ok := err.(*dataPlaneError); ok && e.validation() {
+		return &models.ConfigValidation{IsValid: false, Errors: e.lines()}, nil
+	}
+	return nil, err
+}
@@ -0,0 +1,152 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/anveesa/proxera/models"
+)
+
+// SetServerState implements ServerStateController. The admin state goes
+// through the Data Plane runtime endpoint, which talks to the stats socket;
+// the weight, which that endpoint cannot change, is set on the stats
+// socket directly. Neither needs a reload, and neither touches the
+// configuration, so both last until HAProxy next reloads.
+func (a *HAProxyAdapter) SetServerState(ctx context.Context, backend, name string, change models.ServerStateChange) (*models.BackendServerState, error) {
+	if _, err := a.dataPlaneVersion(ctx); err != nil {
+		return nil, err
+	}
+
+	var weight *int
+	if change.Weight != nil {
+		w, err := a.setServerWeight(ctx, backend, name, *change.Weight)
+		if err != nil {
+			return nil, fmt.Errorf("set weight: %w", err)
+		}
+		weight = &w
+	}
+
+	path, query := a.serverPath("/runtime", backend, name)
+	if change.AdminState != "" {
+		body, _ := json.Marshal(map[string]string{"admin_state": change.AdminState})
+		if _, err := a.dataPlane(ctx, http.MethodPut, path, query, "application/json", body); err != nil {
+			return nil, fmt.Errorf("set admin state: %w", err)
+		}
+	}
+
+	resp, err := a.dataPlane(ctx, http.MethodGet, path, query, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	var rt struct {
+		Name             string `json:"name"`
+		Address          string `json:"address"`
+		Port             *int   `json:"port"`
+		AdminState       string `json:"admin_state"`
+		OperationalState string `json:"operational_state"`
+	}
+	if err := json.Unmarshal(resp.body, &rt); err != nil {
+		return nil, fmt.Errorf("decode runtime server: %w", err)
+	}
+	state := &models.BackendServerState{
+		Backend:          backend,
+		Name:             name,
+		Address:          rt.Address,
+		AdminState:       rt.AdminState,
+		OperationalState: rt.OperationalState,
+		Weight:           weight,
+	}
+	if rt.Port != nil {
+		state.Port = *rt.Port
+	}
+	return state, nil
+}
+
+// haproxyNameRe matches a backend or server name, which is sent to the
+// stats socket as part of a command line.
+var haproxyNameRe = regexp.MustCompile(`^[A-Za-z0-9_.:-]+$`)
+
+// setServerWeight sets a server's weight over the stats socket and returns
+// the weight HAProxy now reports.
+func (a *HAProxyAdapter) setServerWeight(ctx context.Context, backend, name string, weight int) (int, error) {
+	if a.socket == nil {
+		return 0, &ErrNotSupported{Op: "weight changes without haproxy.statsSocket"}
+	}
+	if !haproxyNameRe.MatchString(backend) || !haproxyNameRe.MatchString(name) {
+		return 0, fmt.Errorf("invalid backend or server name %q/%q", backend, name)
+	}
+	target := backend + "/" + name
+	// set weight prints nothing on success.
+	if out, err := a.socket.command(ctx, fmt.Sprintf("set weight %s %d", target, weight)); err != nil {
+		return 0, err
+	} else if strings.HasPrefix(out, "No such") {
+		return 0, fmt.Errorf("%w: %s", ErrNotFound, out)
+	} else if out != "" {
+		return 0, errors.New(out)
+	}
+	// get weight prints the current and configured weights: "10 (initial 1)".
+	out, err := a.socket.command(ctx, "get weight "+target)
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("get weight: empty reply")
+	}
+	w, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, fmt.Errorf("get weight: %s", out)
+	}
+	return w, nil
+}
+
+// haproxySocket sends commands to HAProxy's stats socket on its host, over
+// SSH with socat.
+type haproxySocket struct {
+	serverID string
+	host     string
+	port     int
+	ssh      SSHConfig
+	pool     *SSHPool
+	path     string
+}
+
+// newHAProxySocket returns the stats socket at path, or nil if path is
+// empty.
+func newHAProxySocket(serverID, host string, port int, sshCfg SSHConfig, path string, pool *SSHPool) *haproxySocket {
+	if path == "" {
+		return nil
+	}
+	return &haproxySocket{serverID: serverID, host: host, port: port, ssh: sshCfg, pool: pool, path: path}
+}
+
+// command runs one stats socket command and returns its reply.
+func (s *haproxySocket) command(ctx context.Context, cmd string) (string, error) {
+	client, err := s.pool.Get(ctx, s.serverID, s.host, s.port, s.ssh)
+	if err != nil {
+		return "", fmt.Errorf("ssh connect: %w", err)
+	}
+	out, err := runCommand(client, "socat stdio UNIX-CONNECT:"+shellQuote(s.path), strings.NewReader(cmd+"\n"))
+	if err != nil {
+		return "", fmt.Errorf("stats socket: %s", commandError(out, err))
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// serverPath returns the endpoint for one server of a backend below base
+// ("/runtime" or "/configuration"), in the layout of the API version in use.
+func (a *HAProxyAdapter) serverPath(base, backend, name string) (string, url.Values) {
+	query := url.Values{}
+	if a.apiVersion == "v2" {
+		query.Set("backend", backend)
+		return base + "/servers/" + url.PathEscape(name), query
+	}
+	return base + "/backends/" + url.PathEscape(backend) + "/servers/" + url.PathEscape(name), query
+}
@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anveesa/proxera/models"
+)
+
+// haproxyStat is one row of the stats CSV, by column name.
+type haproxyStat map[string]string
+
+func (s haproxyStat) num(col string) float64 {
+	v, _ := strconv.ParseFloat(strings.TrimSpace(s[col]), 64)
+	return v
+}
+
+func (s haproxyStat) has(col string) bool {
+	return strings.TrimSpace(s[col]) != ""
+}
+
+// parseHAProxyStats reads the `show stat` CSV, whose header line starts
+// with "# ".
+func parseHAProxyStats(body []byte) ([]haproxyStat, error) {
+	text := strings.TrimPrefix(strings.TrimSpace(string(body)), "# ")
+	r := csv.NewReader(strings.NewReader(text))
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse stats CSV: %w", err)
+	}
+	if len(records) < 1 {
+		return nil, fmt.Errorf("stats CSV is empty")
+	}
+	headers := records[0]
+	rows := make([]haproxyStat, 0, len(records)-1)
+	for _, rec := range records[1:] {
+		row := make(haproxyStat, len(headers))
+		for i, h := range headers {
+			if i < len(rec) {
+				row[strings.TrimSpace(h)] = rec[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// haproxyMetrics turns stats rows into server metrics, counting only
+// FRONTEND and BACKEND rows (server rows repeat their backend's traffic).
+//
+// Totals come from the frontends, where every request enters: the rate is
+// req_rate (HTTP) or the session rate (TCP), and errors are 5xx responses
+// plus invalid requests. Backend connection errors (econ) already surface
+// as 5xx on the frontend, so they only count in the backend breakdown.
+// HAProxy reports average times rather than percentiles; P50Latency holds
+// the request-weighted average backend response time (total session time
+// for TCP backends). Error rates and byte rates come from the growth of
+// the cumulative counters since the previous sample, falling back to
+// lifetime ratios on the first one.
+func haproxyMetrics(serverID string, rows []haproxyStat) *models.ServerMetrics {
+	m := &models.ServerMetrics{
+		ServerID:  serverID,
+		Timestamp: time.Now(),
+	}
+
+	type section struct {
+		detail   models.MetricsDetail
+		key      string
+		requests float64
+		errors   float64
+	}
+	var sections []section
+	counters := map[string]float64{}
+	for _, row := range rows {
+		kind := ""
+		switch row["svname"] {
+		case "FRONTEND":
+			kind = "frontend"
+		case "BACKEND":
+			kind = "backend"
+		default:
+			continue
+		}
+		s := section{key: kind + "/" + row["pxname"] + "/"}
+		s.detail = models.MetricsDetail{
+			Kind:              kind,
+			Name:              row["pxname"],
+			Status:            row["status"],
+			ActiveConnections: int(row.num("scur")),
+			AvgResponseTime:   row.num("rtime"),
+			AvgTotalTime:      row.num("ttime"),
+		}
+		if row.has("req_rate") {
+			s.detail.RequestsPerSec = row.num("req_rate")
+		} else {
+			s.detail.RequestsPerSec = row.num("rate")
+		}
+		if row.has("req_tot") {
+			s.requests = row.num("req_tot")
+		} else {
+			s.requests = row.num("stot")
+		}
+		s.errors = row.num("hrsp_5xx")
+		if kind == "frontend" {
+			s.errors += row.num("ereq")
+		} else {
+			s.errors += row.num("econ")
+		}
+		counters[s.key+"requests"] = s.requests
+		counters[s.key+"errors"] = s.errors
+		counters[s.key+"bin"] = row.num("bin")
+		counters[s.key+"bout"] = row.num("bout")
+		sections = append(sections, s)
+	}
+
+	deltas, seconds, haveDeltas := counterDeltas(serverID, counters)
+	m.Cumulative = !haveDeltas
+	var requests, errors, latencySum, latencyWeight float64
+	for _, s := range sections {
+		reqs, errs := s.requests, s.errors
+		if haveDeltas {
+			reqs, errs = deltas[s.key+"requests"], deltas[s.key+"errors"]
+			s.detail.NetworkIn = deltas[s.key+"bin"] / seconds
+			s.detail.NetworkOut = deltas[s.key+"bout"] / seconds
+		}
+		if reqs > 0 {
+			s.detail.ErrorRate = errs / reqs * 100
+		}
+
+		if s.detail.Kind == "frontend" {
+			m.RequestsPerSec += s.detail.RequestsPerSec
+			m.ActiveConnections += s.detail.ActiveConnections
+			m.NetworkIn += s.detail.NetworkIn
+			m.NetworkOut += s.detail.NetworkOut
+			requests += reqs
+			errors += errs
+		} else {
+			latency := s.detail.AvgResponseTime
+			if latency == 0 {
+				latency = s.detail.AvgTotalTime
+			}
+			if latency > 0 && reqs > 0 {
+				latencySum += latency * reqs
+				latencyWeight += reqs
+			}
+		}
+		m.Details = append(m.Details, s.detail)
+	}
+	if requests > 0 {
+		m.ErrorRate = errors / requests * 100
+	}
+	if latencyWeight > 0 {
+		m.P50Latency = latencySum / latencyWeight
+	}
+	return m
+}
@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SSH hops a host key can belong to.
+const (
+	HopTarget = "target"
+	HopJump   = "jump"
+)
+
+// HostKeyID is the store key for a server's hop. The target hop uses the
+// bare server ID.
+func HostKeyID(serverID, hop string) string {
+	if hop == HopJump {
+		return serverID + "#" + HopJump
+	}
+	return serverID
+}
+
+// ParseHostKeyID splits a store key back into server ID and hop.
+func ParseHostKeyID(id string) (serverID, hop string) {
+	if s, ok := strings.CutSuffix(id, "#"+HopJump); ok {
+		return s, HopJump
+	}
+	return id, HopTarget
+}
+
+// HostKeyStore persists pinned SSH host keys, keyed by HostKeyID. SSHPool
+// checks every new connection against it: the first key seen for a hop is
+// pinned (trust on first use) and a different key later is refused.
+type HostKeyStore interface {
+	// PinnedKey returns the trusted key for id, or nil if none is pinned.
+	PinnedKey(id string) (ssh.PublicKey, error)
+	// Pin records key as the trusted key for id.
+	Pin(id, addr string, key ssh.PublicKey) error
+	// Reject records that id presented key instead of its pinned key.
+	Reject(id, addr string, key ssh.PublicKey)
+}
+
+// HostKeyMismatchError is returned when a server's SSH host key differs
+// from the pinned one.
+type HostKeyMismatchError struct {
+	Addr        string
+	Fingerprint string
+}
+
+func (e *HostKeyMismatchError) Error() string {
+	return fmt.Sprintf("ssh host key for %s changed (now %s); approve the new key to reconnect", e.Addr, e.Fingerprint)
+}
+
+// SetHostKeyStore sets the store used to verify host keys. Without one,
+// every SSH connection is refused.
+func (p *SSHPool) SetHostKeyStore(store HostKeyStore) {
+	p.mu.Lock()
+	p.hostKeys = store
+	p.mu.Unlock()
+}
+
+func (p *SSHPool) hostKeyCallback(id string) ssh.HostKeyCallback {
+	p.mu.RLock()
+	store := p.hostKeys
+	p.mu.RUnlock()
+
+	return func(hostname string, _ net.Addr, key ssh.PublicKey) error {
+		if store == nil {
+			return errors.New("no ssh host key store configured")
+		}
+		pinned, err := store.PinnedKey(id)
+		if err != nil {
+			return fmt.Errorf("load pinned host key: %w", err)
+		}
+		if pinned == nil {
+			return store.Pin(id, hostname, key)
+		}
+		if bytes.Equal(pinned.Marshal(), key.Marshal()) {
+			return nil
+		}
+		store.Reject(id, hostname, key)
+		return &HostKeyMismatchError{Addr: hostname, Fingerprint: ssh.FingerprintSHA256(key)}
+	}
+}
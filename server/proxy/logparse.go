@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"strings"
+
+	"github.com/anveesa/proxera/models"
+)
+
+// logParser reads one log line in one format, or returns nil if the line
+// is not in that format.
+type logParser func(line string) *models.LogEntry
+
+// logParsers lists, by proxy type and log kind, the formats each proxy
+// writes, in the order they are tried. Proxy types not listed try them all.
+var logParsers = map[models.ProxyType]map[string][]logParser{
+	models.ProxyNGINX: {
+		models.LogKindAccess: {parseNGINXJSONLog, parseCombinedLog},
+		models.LogKindError:  {parseNGINXErrorLog},
+	},
+	models.ProxyCaddy: {
+		models.LogKindAccess: {parseCaddyJSONLog},
+		models.LogKindError:  {parseJSONErrorLog},
+	},
+	models.ProxyHAProxy: {
+		models.LogKindAccess: {parseHAProxyHTTPLog},
+		models.LogKindError:  {parseHAProxyErrorLog},
+	},
+	models.ProxyTraefik: {
+		models.LogKindAccess: {parseTraefikJSONLog, parseCombinedLog},
+		models.LogKindError:  {parseJSONErrorLog, parseTraefikErrorLog},
+	},
+}
+
+func logParsersFor(proxyType models.ProxyType, kind string) []logParser {
+	if byKind, ok := logParsers[proxyType]; ok {
+		return byKind[kind]
+	}
+	var all []logParser
+	for _, t := range []models.ProxyType{models.ProxyCaddy, models.ProxyTraefik, models.ProxyNGINX, models.ProxyHAProxy} {
+		all = append(all, logParsers[t][kind]...)
+	}
+	return all
+}
+
+// ParseLogLine parses a line of proxyType's log of the given kind. ok is
+// false if the line is in none of that log's formats.
+func ParseLogLine(proxyType models.ProxyType, kind, line string) (*models.LogEntry, bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		return nil, false
+	}
+	for _, parse := range logParsersFor(proxyType, kind) {
+		if e := parse(trimmed); e != nil {
+			e.Kind = kind
+			if kind == models.LogKindAccess {
+				e.Level = accessLevel(e.Status)
+			}
+			e.Message = line
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// ParseAccessLog parses an access log line, and reports false for any
+// other line, such as an error log entry.
+func ParseAccessLog(proxyType models.ProxyType, line string) (*models.LogEntry, bool) {
+	return ParseLogLine(proxyType, models.LogKindAccess, line)
+}
+
+// ParseLog parses a line from any of proxyType's logs. A line in no known
+// format comes back with just its message, at info level.
+func ParseLog(proxyType models.ProxyType, line string) *models.LogEntry {
+	for _, kind := range []string{models.LogKindAccess, models.LogKindError} {
+		if e, ok := ParseLogLine(proxyType, kind, line); ok {
+			return e
+		}
+	}
+	return &models.LogEntry{Level: "info", Message: line}
+}
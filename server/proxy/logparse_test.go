@@ -0,0 +1,187 @@
+package proxy
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/anveesa/proxera/models"
+)
+
+func ms(v float64) *float64 { return &v }
+
+func TestParseLogLine(t *testing.T) {
+	utc := func(nsec int) time.Time { return time.Date(2026, 10, 10, 13, 55, 36, nsec, time.UTC) }
+	local := func(nsec int) time.Time { return time.Date(2026, 10, 10, 13, 55, 36, nsec, time.Local) }
+	access, errlog := models.LogKindAccess, models.LogKindError
+
+	tests := []struct {
+		name      string
+		proxyType models.ProxyType
+		kind      string
+		line      string
+		want      *models.LogEntry // nil if the line must not parse
+	}{
+		// NGINX
+		{"nginx combined", models.ProxyNGINX, access,
+			`10.0.0.1 - - [10/Oct/2026:13:55:36 +0000] "GET /a?b=1 HTTP/1.1" 404 512 "-" "curl/8.0"`,
+			&models.LogEntry{Timestamp: utc(0), Level: "warn", ClientIP: "10.0.0.1", Method: "GET", Path: "/a?b=1", Status: 404, Bytes: 512}},
+		{"nginx combined with fields", models.ProxyNGINX, access,
+			`10.0.0.1 - alice [10/Oct/2026:13:55:36 +0000] "POST /api HTTP/1.1" 502 0 "https://example.com/" "Mozilla/5.0 (X11)" rt=0.125 ua="10.0.0.5:3000" host=api.example.com`,
+			&models.LogEntry{Timestamp: utc(0), Level: "error", ClientIP: "10.0.0.1", Method: "POST", Host: "api.example.com", Path: "/api",
+				Status: 502, Duration: ms(125), Upstream: "10.0.0.5:3000"}},
+		{"nginx combined without upstream", models.ProxyNGINX, access,
+			`10.0.0.1 - - [10/Oct/2026:13:55:36 +0000] "GET / HTTP/1.1" 200 - "-" "-" rt=0.000 ua="-"`,
+			&models.LogEntry{Timestamp: utc(0), Level: "info", ClientIP: "10.0.0.1", Method: "GET", Path: "/", Status: 200, Duration: ms(0)}},
+		{"nginx json", models.ProxyNGINX, access,
+			`{"time_iso8601":"2026-10-10T13:55:36+00:00","remote_addr":"10.0.0.1","request_method":"POST","host":"api.example.com","request_uri":"/v1/users","status":"201","body_bytes_sent":"42","request_time":"0.050","upstream_addr":"10.0.0.5:3000"}`,
+			&models.LogEntry{Timestamp: utc(0), Level: "info", ClientIP: "10.0.0.1", Method: "POST", Host: "api.example.com", Path: "/v1/users",
+				Status: 201, Bytes: 42, Duration: ms(50), Upstream: "10.0.0.5:3000"}},
+		{"nginx json with request line", models.ProxyNGINX, access,
+			`{"time_local":"10/Oct/2026:13:55:36 +0000","remote_addr":"10.0.0.1","request":"GET /x HTTP/1.1","status":"304","upstream_addr":"-"}`,
+			&models.LogEntry{Timestamp: utc(0), Level: "info", ClientIP: "10.0.0.1", Method: "GET", Path: "/x", Status: 304}},
+		{"nginx error", models.ProxyNGINX, errlog,
+			`2026/10/10 13:55:36 [error] 1234#1234: *5 connect() failed (111: Connection refused) while connecting to upstream, client: 10.0.0.1, server: example.com, request: "GET /api HTTP/1.1", upstream: "http://10.0.0.5:3000/api", host: "example.com"`,
+			&models.LogEntry{Timestamp: local(0), Level: "error", ClientIP: "10.0.0.1", Method: "GET", Host: "example.com", Path: "/api",
+				Upstream: "http://10.0.0.5:3000/api"}},
+		{"nginx error without request", models.ProxyNGINX, errlog,
+			`2026/10/10 13:55:36 [warn] 1#1: conflicting server name "a.com" on 0.0.0.0:80, ignored`,
+			&models.LogEntry{Timestamp: local(0), Level: "warn"}},
+		{"nginx emerg", models.ProxyNGINX, errlog,
+			`2026/10/10 13:55:36 [emerg] 1#1: unknown directive "foo" in /etc/nginx/nginx.conf:12`,
+			&models.LogEntry{Timestamp: local(0), Level: "error"}},
+		{"nginx garbage", models.ProxyNGINX, access, `not a log line`, nil},
+		{"nginx truncated combined", models.ProxyNGINX, access, `10.0.0.1 - - [10/Oct/2026:13:55:36 +0000] "GET /a HTTP/1.1"`, nil},
+		{"nginx json without status", models.ProxyNGINX, access, `{"remote_addr":"10.0.0.1","request_uri":"/"}`, nil},
+		{"nginx broken json", models.ProxyNGINX, access, `{"status":`, nil},
+		{"nginx error without level", models.ProxyNGINX, errlog, `2026/10/10 13:55:36 1#1: something`, nil},
+		{"nginx error line as access", models.ProxyNGINX, access, `2026/10/10 13:55:36 [error] 1#1: open() failed`, nil},
+
+		// Caddy
+		{"caddy access", models.ProxyCaddy, access,
+			`{"level":"info","ts":1760104536.5,"logger":"http.log.access.log0","msg":"handled request","request":{"remote_ip":"10.0.0.1","remote_port":"51234","client_ip":"10.0.0.2","proto":"HTTP/2.0","method":"GET","host":"example.com","uri":"/index.html","headers":{"User-Agent":["curl/8.0"]}},"bytes_read":0,"user_id":"","duration":0.004,"size":512,"status":200,"resp_headers":{}}`,
+			&models.LogEntry{Timestamp: time.Unix(1760104536, 5e8), Level: "info", ClientIP: "10.0.0.2", Method: "GET", Host: "example.com",
+				Path: "/index.html", Status: 200, Bytes: 512, Duration: ms(4)}},
+		{"caddy error", models.ProxyCaddy, errlog,
+			`{"level":"error","ts":1760104536.5,"logger":"http.log.error","msg":"dial tcp 10.0.0.5:3000: connect: connection refused","request":{"remote_ip":"10.0.0.1","method":"GET","host":"example.com","uri":"/api"},"duration":0.001,"status":502}`,
+			&models.LogEntry{Timestamp: time.Unix(1760104536, 5e8), Level: "error", ClientIP: "10.0.0.1", Method: "GET", Host: "example.com",
+				Path: "/api", Status: 502}},
+		{"caddy runtime log", models.ProxyCaddy, errlog,
+			`{"level":"warn","ts":1760104536.5,"logger":"tls","msg":"stapling OCSP","error":"no OCSP stapling for [example.com]"}`,
+			&models.LogEntry{Timestamp: time.Unix(1760104536, 5e8), Level: "warn"}},
+		{"caddy error as access", models.ProxyCaddy, access,
+			`{"level":"error","ts":1760104536.5,"logger":"http.log.error","msg":"x","request":{"method":"GET"},"status":502}`, nil},
+		{"caddy access without status", models.ProxyCaddy, access,
+			`{"level":"info","ts":1760104536.5,"logger":"http.log.access","request":{"method":"GET"}}`, nil},
+		{"caddy console format", models.ProxyCaddy, access,
+			`2026/10/10 13:55:36.000	INFO	http.log.access	handled request`, nil},
+		{"caddy error without level", models.ProxyCaddy, errlog, `{"ts":1760104536.5,"msg":"x"}`, nil},
+
+		// Traefik
+		{"traefik clf", models.ProxyTraefik, access,
+			`10.0.0.1 - - [10/Oct/2026:13:55:36 +0000] "GET /api HTTP/1.1" 200 512 "-" "curl/8.0" 42 "api-router@docker" "http://10.0.0.5:3000" 7ms`,
+			&models.LogEntry{Timestamp: utc(0), Level: "info", ClientIP: "10.0.0.1", Method: "GET", Path: "/api", Status: 200, Bytes: 512,
+				Duration: ms(7), Upstream: "http://10.0.0.5:3000"}},
+		{"traefik clf without server", models.ProxyTraefik, access,
+			`10.0.0.1 - - [10/Oct/2026:13:55:36 +0000] "GET /missing HTTP/1.1" 404 19 "-" "-" 43 "-" "-" 0ms`,
+			&models.LogEntry{Timestamp: utc(0), Level: "warn", ClientIP: "10.0.0.1", Method: "GET", Path: "/missing", Status: 404, Bytes: 19,
+				Duration: ms(0)}},
+		{"traefik json", models.ProxyTraefik, access,
+			`{"ClientHost":"10.0.0.1","DownstreamContentSize":512,"DownstreamStatus":503,"Duration":7500000,"RequestHost":"example.com","RequestMethod":"GET","RequestPath":"/api","ServiceAddr":"10.0.0.5:3000","ServiceURL":"http://10.0.0.5:3000","StartUTC":"2026-10-10T13:55:36.123456789Z","level":"info","msg":"","time":"2026-10-10T13:55:36Z"}`,
+			&models.LogEntry{Timestamp: utc(123456789), Level: "error", ClientIP: "10.0.0.1", Method: "GET", Host: "example.com", Path: "/api",
+				Status: 503, Bytes: 512, Duration: ms(7.5), Upstream: "10.0.0.5:3000"}},
+		{"traefik v2 error", models.ProxyTraefik, errlog,
+			`time="2026-10-10T13:55:36Z" level=error msg="Error while starting server" entryPointName=web`,
+			&models.LogEntry{Timestamp: utc(0), Level: "error"}},
+		{"traefik v3 console", models.ProxyTraefik, errlog,
+			`2026-10-10T13:55:36Z WRN Router uses a non-existent service error="service \"api@docker\" does not exist" routerName=api@docker`,
+			&models.LogEntry{Timestamp: utc(0), Level: "warn"}},
+		{"traefik json error", models.ProxyTraefik, errlog,
+			`{"level":"error","error":"accept tcp [::]:443: use of closed network connection","entryPointName":"websecure","time":"2026-10-10T13:55:36Z","message":"Error while starting server"}`,
+			&models.LogEntry{Timestamp: utc(0), Level: "error"}},
+		{"traefik json without status", models.ProxyTraefik, access, `{"ClientHost":"10.0.0.1","RequestPath":"/"}`, nil},
+		{"traefik error without msg", models.ProxyTraefik, errlog, `time="2026-10-10T13:55:36Z" level=error`, nil},
+		{"traefik unknown console level", models.ProxyTraefik, errlog, `2026-10-10T13:55:36Z XXX something`, nil},
+
+		// HAProxy
+		{"haproxy httplog", models.ProxyHAProxy, access,
+			`Oct 10 13:55:36 lb haproxy[1234]: 10.0.1.2:33317 [10/Oct/2026:13:55:36.655] http-in static/srv1 10/0/30/69/109 200 2750 - - ---- 1/1/1/1/0 0/0 "GET /i HTTP/1.1"`,
+			&models.LogEntry{Timestamp: local(655e6), Level: "info", ClientIP: "10.0.1.2", Method: "GET", Path: "/i", Status: 200, Bytes: 2750,
+				Duration: ms(109), Upstream: "static/srv1"}},
+		{"haproxy httplog on stdout", models.ProxyHAProxy, access,
+			`10.0.1.2:33317 [10/Oct/2026:13:55:36.655] https-in~ app/<NOSRV> 0/-1/-1/-1/+5001 503 217 - - SC-- 3/3/0/0/0 0/0 {example.com} "POST /upload HTTP/1.1"`,
+			&models.LogEntry{Timestamp: local(655e6), Level: "error", ClientIP: "10.0.1.2", Method: "POST", Path: "/upload", Status: 503, Bytes: 217,
+				Duration: ms(5001), Upstream: "app/<NOSRV>"}},
+		{"haproxy aborted request", models.ProxyHAProxy, access,
+			`10.0.1.2:33317 [10/Oct/2026:13:55:36.655] http-in app/web1 0/0/0/-1/3 -1 0 - - CD-- 1/1/0/0/0 0/0 "GET / HTTP/1.1"`,
+			&models.LogEntry{Timestamp: local(655e6), Level: "info", ClientIP: "10.0.1.2", Method: "GET", Path: "/", Duration: ms(3), Upstream: "app/web1"}},
+		{"haproxy server down", models.ProxyHAProxy, errlog,
+			`[WARNING]  (1) : Server app/web1 is DOWN, reason: Layer4 timeout, check duration: 2001ms. 0 active and 0 backup servers left.`,
+			&models.LogEntry{Level: "warn", Upstream: "app/web1"}},
+		{"haproxy alert", models.ProxyHAProxy, errlog,
+			`[ALERT]    (1) : config : parsing [/etc/haproxy/haproxy.cfg:12] : unknown keyword 'foo' in 'backend' section`,
+			&models.LogEntry{Level: "error"}},
+		{"haproxy syslog server up", models.ProxyHAProxy, errlog,
+			`Oct 10 13:55:36 lb haproxy[1234]: Server app/web1 is UP, reason: Layer7 check passed, code: 200, check duration: 3ms.`,
+			&models.LogEntry{Timestamp: time.Date(time.Now().Year(), 10, 10, 13, 55, 36, 0, time.Local), Level: "info", Upstream: "app/web1"}},
+		{"haproxy tcplog", models.ProxyHAProxy, access,
+			`10.0.1.2:33317 [10/Oct/2026:13:55:36.655] tcp-in app/web1 0/0/5007 212 -- 1/1/0/0/0 0/0`, nil},
+		{"haproxy plain text", models.ProxyHAProxy, errlog, `Proxy http-in started.`, nil},
+
+		{"empty line", models.ProxyNGINX, access, "   ", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseLogLine(tt.proxyType, tt.kind, tt.line)
+			if tt.want == nil {
+				if ok {
+					t.Fatalf("ParseLogLine() = %s, want no match", entryString(got))
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("ParseLogLine() did not match")
+			}
+			want := *tt.want
+			want.Kind, want.Message = tt.kind, tt.line
+			if !entriesEqual(*got, want) {
+				t.Errorf("ParseLogLine() =\n%s\nwant\n%s", entryString(got), entryString(&want))
+			}
+		})
+	}
+}
+
+func TestParseLogUnknownLine(t *testing.T) {
+	line := "Proxy http-in started."
+	got := ParseLog(models.ProxyHAProxy, line)
+	want := models.LogEntry{Level: "info", Message: line}
+	if !entriesEqual(*got, want) {
+		t.Errorf("ParseLog() = %s, want %s", entryString(got), entryString(&want))
+	}
+
+	got = ParseLog(models.ProxyNGINX, "2026/10/10 13:55:36 [crit] 1#1: out of memory")
+	if got.Kind != models.LogKindError || got.Level != "error" {
+		t.Errorf("ParseLog() kind, level = %q, %q, want error, error", got.Kind, got.Level)
+	}
+}
+
+func entriesEqual(a, b models.LogEntry) bool {
+	if !a.Timestamp.Equal(b.Timestamp) || (a.Duration == nil) != (b.Duration == nil) {
+		return false
+	}
+	if a.Duration != nil && *a.Duration != *b.Duration {
+		return false
+	}
+	a.Timestamp, b.Timestamp = time.Time{}, time.Time{}
+	a.Duration, b.Duration = nil, nil
+	return a == b
+}
+
+func entryString(e *models.LogEntry) string {
+	d := "nil"
+	if e.Duration != nil {
+		d = fmt.Sprint(*e.Duration)
+	}
+	return fmt.Sprintf("{Timestamp:%s Kind:%s Level:%s ClientIP:%s Method:%s Host:%s Path:%s Status:%d Bytes:%d Duration:%s Upstream:%s}",
+		e.Timestamp.Format(time.RFC3339Nano), e.Kind, e.Level, e.ClientIP, e.Method, e.Host, e.Path, e.Status, e.Bytes, d, e.Upstream)
+}
@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/anveesa/proxera/models"
+	"golang.org/x/crypto/ssh"
+)
+
+// logSource follows a server's logs on its host over SSH, by tailing
+// files, following a systemd journal or following a container's output.
+type logSource struct {
+	serverID string
+	host     string
+	port     int
+	ssh      SSHConfig
+	pool     *SSHPool
+	command  string
+}
+
+// newLogSource returns the log source the settings ask for, or nil if they
+// name none.
+func newLogSource(serverID, host string, port int, sshCfg SSHConfig, settings models.LogSettings, pool *SSHPool) *logSource {
+	var script string
+	switch settings.Source {
+	case models.LogSourceFile:
+		for _, p := range []string{settings.AccessLogPath, settings.ErrorLogPath} {
+			if p != "" {
+				script += " " + shellQuote(p)
+			}
+		}
+		if script == "" {
+			return nil
+		}
+		script = "tail -F" + script + " 2>/dev/null"
+	case models.LogSourceJournal:
+		// -o cat leaves out the journal's own prefix, so lines read as the
+		// proxy wrote them.
+		script = "journalctl -f -n 10 -o cat -u " + shellQuote(settings.Unit)
+	case models.LogSourceDocker:
+		script = "docker logs -f --tail 10 " + shellQuote(settings.Container) + " 2>&1"
+	default:
+		return nil
+	}
+	return &logSource{
+		serverID: serverID,
+		host:     host,
+		port:     port,
+		ssh:      sshCfg,
+		pool:     pool,
+		command:  wrapCommand(script, settings.UseSudo != nil && *settings.UseSudo, ""),
+	}
+}
+
+func (s *logSource) tail(ctx context.Context) (io.ReadCloser, error) {
+	client, err := s.pool.Get(ctx, s.serverID, s.host, s.port, s.ssh)
+	if err != nil {
+		return nil, fmt.Errorf("ssh connect: %w", err)
+	}
+	return streamCommand(ctx, client, s.command)
+}
+
+// tailLogs follows the log source if there is one.
+func tailLogs(ctx context.Context, s *logSource) (io.ReadCloser, error) {
+	if s == nil {
+		return nil, &ErrNotSupported{Op: "TailLogs"}
+	}
+	return s.tail(ctx)
+}
+
+// streamCommand starts cmd on client and returns its combined output as it
+// is written. The command is stopped when ctx ends.
+func streamCommand(ctx context.Context, client *ssh.Client, cmd string) (io.ReadCloser, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	session.Stdout = pw
+	session.Stderr = pw
+
+	if err := session.Start(cmd); err != nil {
+		session.Close()
+		pw.Close()
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		session.Close()
+		pw.Close()
+	}()
+
+	go func() {
+		session.Wait() //nolint:errcheck
+		pw.Close()
+	}()
+
+	return pr, nil
+}
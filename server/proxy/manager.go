@@ -2,10 +2,12 @@ package proxy
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
+	"strconv"
 	"sync"
 	"time"
 
@@ -13,56 +15,140 @@ import (
 	"golang.org/x/crypto/ssh"
 )
 
+// SSHAuth holds the credentials for one SSH hop. A private key may be
+// passphrase-protected; password auth is tried after the key, if set.
+type SSHAuth struct {
+	User       string
+	PrivateKey string // PEM
+	Passphrase string // for an encrypted PrivateKey
+	Password   string
+}
+
+// SSHJump is a bastion the target is reached through.
+type SSHJump struct {
+	Host string
+	Port int
+	SSHAuth
+}
+
+// SSHConfig describes how to reach a server over SSH.
+type SSHConfig struct {
+	SSHAuth
+	Jump *SSHJump // nil to dial the server directly
+}
+
 // SSHPool manages a pool of reusable SSH client connections.
 type SSHPool struct {
-	mu      sync.RWMutex
-	clients map[string]*poolEntry
+	mu       sync.RWMutex
+	clients  map[string]*poolEntry
+	dialing  map[string]*sync.Mutex // held while a server is being dialled
+	hostKeys HostKeyStore
 }
 
 type poolEntry struct {
 	client   *ssh.Client
+	jump     *ssh.Client // bastion hop, if any
 	lastUsed time.Time
 }
 
+func (e *poolEntry) close() {
+	e.client.Close()
+	if e.jump != nil {
+		e.jump.Close()
+	}
+}
+
 func NewSSHPool() *SSHPool {
 	p := &SSHPool{
 		clients: make(map[string]*poolEntry),
+		dialing: make(map[string]*sync.Mutex),
 	}
 	go p.evictLoop()
 	return p
 }
 
-// Get returns an existing or new SSH client for the given server.
-func (p *SSHPool) Get(ctx context.Context, serverID, host string, port int, user, privKeyPEM string) (*ssh.Client, error) {
-	p.mu.RLock()
-	entry, exists := p.clients[serverID]
-	p.mu.RUnlock()
-
-	if exists {
+// Get returns an existing or new SSH client for the given server, dialling
+// through its jump host when one is configured. Concurrent calls for the
+// same server share a single dial.
+func (p *SSHPool) Get(ctx context.Context, serverID, host string, port int, cfg SSHConfig) (*ssh.Client, error) {
+	if entry := p.lookup(serverID); entry != nil {
 		// Validate connection with a test session
 		sess, err := entry.client.NewSession()
 		if err == nil {
 			sess.Close()
-			entry.lastUsed = time.Now()
 			return entry.client, nil
 		}
 		// Connection stale — remove it
-		p.Evict(serverID)
+		p.drop(serverID, entry)
+	}
+
+	dial := p.dialLock(serverID)
+	dial.Lock()
+	defer dial.Unlock()
+	// Another caller may have connected while this one waited.
+	if entry := p.lookup(serverID); entry != nil {
+		return entry.client, nil
 	}
 
 	// Create new connection
-	client, err := dialSSH(ctx, host, port, user, privKeyPEM)
+	entry := &poolEntry{lastUsed: time.Now()}
+	var err error
+	if cfg.Jump != nil {
+		entry.jump, err = dialSSH(ctx, nil, cfg.Jump.Host, cfg.Jump.Port, cfg.Jump.SSHAuth, p.hostKeyCallback(HostKeyID(serverID, HopJump)))
+		if err != nil {
+			return nil, fmt.Errorf("jump host %s: %w", cfg.Jump.Host, err)
+		}
+	}
+	entry.client, err = dialSSH(ctx, entry.jump, host, port, cfg.SSHAuth, p.hostKeyCallback(HostKeyID(serverID, HopTarget)))
 	if err != nil {
+		if entry.jump != nil {
+			entry.jump.Close()
+		}
 		return nil, err
 	}
 
 	p.mu.Lock()
-	p.clients[serverID] = &poolEntry{client: client, lastUsed: time.Now()}
+	p.clients[serverID] = entry
 	p.mu.Unlock()
 
-	go p.keepalive(serverID, client)
+	go p.keepalive(serverID, entry)
+
+	return entry.client, nil
+}
+
+// lookup returns the pooled entry for a server, marking it used, or nil.
+func (p *SSHPool) lookup(serverID string) *poolEntry {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entry, ok := p.clients[serverID]
+	if !ok {
+		return nil
+	}
+	entry.lastUsed = time.Now()
+	return entry
+}
 
-	return client, nil
+// dialLock returns the mutex serialising dials to a server.
+func (p *SSHPool) dialLock(serverID string) *sync.Mutex {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	m, ok := p.dialing[serverID]
+	if !ok {
+		m = &sync.Mutex{}
+		p.dialing[serverID] = m
+	}
+	return m
+}
+
+// drop closes a dead entry and removes it from the pool, unless it has
+// already been replaced by a newer connection.
+func (p *SSHPool) drop(serverID string, entry *poolEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entry.close()
+	if p.clients[serverID] == entry {
+		delete(p.clients, serverID)
+	}
 }
 
 // Evict removes and closes the pool entry for a given server.
@@ -70,7 +156,7 @@ func (p *SSHPool) Evict(serverID string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	if entry, ok := p.clients[serverID]; ok {
-		entry.client.Close()
+		entry.close()
 		delete(p.clients, serverID)
 	}
 }
@@ -83,7 +169,7 @@ func (p *SSHPool) evictLoop() {
 		cutoff := time.Now().Add(-10 * time.Minute)
 		for id, entry := range p.clients {
 			if entry.lastUsed.Before(cutoff) {
-				entry.client.Close()
+				entry.close()
 				delete(p.clients, id)
 				log.Printf("SSH pool: evicted idle connection for server %s", id)
 			}
@@ -92,48 +178,58 @@ func (p *SSHPool) evictLoop() {
 	}
 }
 
-func (p *SSHPool) keepalive(serverID string, client *ssh.Client) {
+// keepalive pings both hops so idle NAT/firewall state on the bastion path
+// is not dropped while the target connection is still pooled.
+func (p *SSHPool) keepalive(serverID string, entry *poolEntry) {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 	for range ticker.C {
 		p.mu.RLock()
-		entry, ok := p.clients[serverID]
+		current, ok := p.clients[serverID]
 		p.mu.RUnlock()
-		if !ok || entry.client != client {
+		if !ok || current != entry {
 			return
 		}
-		if _, _, err := client.SendRequest("keepalive@proxera", true, nil); err != nil {
-			p.Evict(serverID)
-			return
+		for _, c := range []*ssh.Client{entry.jump, entry.client} {
+			if c == nil {
+				continue
+			}
+			if _, _, err := c.SendRequest("keepalive@proxera", true, nil); err != nil {
+				p.drop(serverID, entry)
+				return
+			}
 		}
 	}
 }
 
-func dialSSH(ctx context.Context, host string, port int, user, privKeyPEM string) (*ssh.Client, error) {
-	var authMethods []ssh.AuthMethod
-
-	if privKeyPEM != "" {
-		signer, err := ssh.ParsePrivateKey([]byte(privKeyPEM))
-		if err != nil {
-			return nil, fmt.Errorf("parse private key: %w", err)
-		}
-		authMethods = append(authMethods, ssh.PublicKeys(signer))
+// dialSSH opens an SSH connection to host:port, directly or — when via is
+// set — tunnelled through that already-connected client.
+func dialSSH(ctx context.Context, via *ssh.Client, host string, port int, auth SSHAuth, hostKeyCallback ssh.HostKeyCallback) (*ssh.Client, error) {
+	authMethods, err := authMethods(auth)
+	if err != nil {
+		return nil, err
 	}
 
 	cfg := &ssh.ClientConfig{
-		User:            user,
+		User:            auth.User,
 		Auth:            authMethods,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint:gosec // TODO: known_hosts in production
+		HostKeyCallback: hostKeyCallback,
 		Timeout:         10 * time.Second,
 	}
 
-	addr := fmt.Sprintf("%s:%d", host, port)
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
 
 	connCh := make(chan net.Conn, 1)
 	errCh := make(chan error, 1)
 	go func() {
-		d := &net.Dialer{Timeout: 10 * time.Second}
-		conn, err := d.DialContext(ctx, "tcp", addr)
+		var conn net.Conn
+		var err error
+		if via != nil {
+			conn, err = via.DialContext(ctx, "tcp", addr)
+		} else {
+			d := &net.Dialer{Timeout: 10 * time.Second}
+			conn, err = d.DialContext(ctx, "tcp", addr)
+		}
 		if err != nil {
 			errCh <- err
 			return
@@ -158,6 +254,48 @@ func dialSSH(ctx context.Context, host string, port int, user, privKeyPEM string
 	return ssh.NewClient(sshConn, chans, reqs), nil
 }
 
+func authMethods(auth SSHAuth) ([]ssh.AuthMethod, error) {
+	var methods []ssh.AuthMethod
+
+	if auth.PrivateKey != "" {
+		var signer ssh.Signer
+		var err error
+		if auth.Passphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(auth.PrivateKey), []byte(auth.Passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey([]byte(auth.PrivateKey))
+		}
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			return nil, errors.New("private key is passphrase-protected but no passphrase is set")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse private key: %w", err)
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+
+	if auth.Password != "" {
+		password := auth.Password
+		methods = append(methods,
+			ssh.Password(password),
+			// Many servers only offer password login as keyboard-interactive.
+			ssh.KeyboardInteractive(func(_, _ string, questions []string, _ []bool) ([]string, error) {
+				answers := make([]string, len(questions))
+				for i := range answers {
+					answers[i] = password
+				}
+				return answers, nil
+			}),
+		)
+	}
+
+	if len(methods) == 0 {
+		return nil, errors.New("no ssh credentials configured (private key or password)")
+	}
+	return methods, nil
+}
+
 // Manager builds the correct adapter for a server.
 type Manager struct {
 	sshPool *SSHPool
@@ -171,33 +309,57 @@ func (m *Manager) GetSSHPool() *SSHPool {
 	return m.sshPool
 }
 
+// AdapterSettings carries the per-proxy-type settings stored on a server.
+type AdapterSettings struct {
+	NGINX            models.NGINXSettings
+	Traefik          models.TraefikSettings
+	HAProxy          models.HAProxySettings
+	HAProxyPassword  string // decrypted
+	TraefikLocalRoot string // local Traefik file directories must be under it
+	Logs             models.LogSettings
+}
+
 // NewAdapter creates the appropriate ProxyAdapter for the given server config.
 func (m *Manager) NewAdapter(
 	serverID, serverName, host string,
 	port int,
 	proxyType, connectionType string,
-	sshUser, sshKey, apiURL, apiToken string,
+	sshCfg SSHConfig, settings AdapterSettings, apiURL, apiToken string,
 ) (ProxyAdapter, error) {
+	// Logs and the HAProxy stats socket are reached over SSH: on the
+	// server's own port if it is managed over SSH, otherwise on the one the
+	// log settings name.
+	hostPort := port
+	if connectionType != string(models.ConnSSH) {
+		hostPort = settings.Logs.SSHPort
+		if hostPort == 0 {
+			hostPort = 22
+		}
+	}
+	logs := newLogSource(serverID, host, hostPort, sshCfg, settings.Logs, m.sshPool)
+
 	switch proxyType {
 	case "nginx":
-		return NewNGINXAdapter(serverID, serverName, host, port, sshUser, sshKey, m.sshPool), nil
+		return NewNGINXAdapter(serverID, serverName, host, port, sshCfg, settings.NGINX, m.sshPool, logs), nil
 	case "traefik":
 		if apiURL == "" {
 			apiURL = fmt.Sprintf("http://%s:%d", host, port)
 		}
-		return NewTraefikAdapter(serverID, serverName, apiURL, apiToken), nil
+		files := newTraefikFiles(serverID, host, sshCfg, settings.Traefik, settings.TraefikLocalRoot, m.sshPool)
+		return NewTraefikAdapter(serverID, serverName, apiURL, apiToken, settings.Traefik, files, logs), nil
 	case "caddy":
 		if apiURL == "" {
 			apiURL = fmt.Sprintf("http://%s:%d", host, port)
 		}
-		return NewCaddyAdapter(serverID, serverName, apiURL), nil
+		return NewCaddyAdapter(serverID, serverName, apiURL, logs), nil
 	case "haproxy":
 		if apiURL == "" {
 			apiURL = fmt.Sprintf("http://%s:%d", host, port)
 		}
-		return NewHAProxyAdapter(serverID, serverName, apiURL, apiToken), nil
+		socket := newHAProxySocket(serverID, host, hostPort, sshCfg, settings.HAProxy.StatsSocket, m.sshPool)
+		return NewHAProxyAdapter(serverID, serverName, apiURL, apiToken, settings.HAProxy, settings.HAProxyPassword, socket, logs), nil
 	default:
-		return &stubAdapter{serverID: serverID, host: host, port: port}, nil
+		return &stubAdapter{serverID: serverID, host: host, port: port, logs: logs}, nil
 	}
 }
 
@@ -206,6 +368,7 @@ type stubAdapter struct {
 	serverID string
 	host     string
 	port     int
+	logs     *logSource // nil when no log source is set
 }
 
 func (s *stubAdapter) Type() string { return "other" }
@@ -235,8 +398,8 @@ func (s *stubAdapter) PutConfig(_ context.Context, _ string) (*models.ConfigVali
 
 func (s *stubAdapter) Reload(_ context.Context) error { return &ErrNotSupported{Op: "Reload"} }
 
-func (s *stubAdapter) TailLogs(_ context.Context) (io.ReadCloser, error) {
-	return nil, &ErrNotSupported{Op: "TailLogs"}
+func (s *stubAdapter) TailLogs(ctx context.Context) (io.ReadCloser, error) {
+	return tailLogs(ctx, s.logs)
 }
 
 func (s *stubAdapter) GetStatus(ctx context.Context) (string, error) {
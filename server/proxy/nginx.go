@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"io"
 	"net"
-	"strconv"
 	"strings"
 	"time"
 
@@ -20,142 +19,137 @@ type NGINXAdapter struct {
 	serverName string
 	host       string
 	port       int
-	sshUser    string
-	sshKey     string // decrypted PEM private key
+	ssh        SSHConfig // decrypted credentials
+	settings   models.NGINXSettings
 	sshPool    *SSHPool
+	logs       *logSource // nil for the default log files
 }
 
-func NewNGINXAdapter(serverID, serverName, host string, port int, sshUser, sshKey string, pool *SSHPool) *NGINXAdapter {
+func NewNGINXAdapter(serverID, serverName, host string, port int, sshCfg SSHConfig, settings models.NGINXSettings, pool *SSHPool, logs *logSource) *NGINXAdapter {
 	return &NGINXAdapter{
 		serverID:   serverID,
 		serverName: serverName,
 		host:       host,
 		port:       port,
-		sshUser:    sshUser,
-		sshKey:     sshKey,
+		ssh:        sshCfg,
+		settings:   settings,
 		sshPool:    pool,
+		logs:       logs,
 	}
 }
 
 func (a *NGINXAdapter) Type() string { return "nginx" }
 
 func (a *NGINXAdapter) getClient(ctx context.Context) (*ssh.Client, error) {
-	return a.sshPool.Get(ctx, a.serverID, a.host, a.port, a.sshUser, a.sshKey)
+	return a.sshPool.Get(ctx, a.serverID, a.host, a.port, a.ssh)
 }
 
-func (a *NGINXAdapter) Ping(ctx context.Context) (int64, error) {
-	start := time.Now()
-	addr := fmt.Sprintf("%s:%d", a.host, a.port)
-	conn, err := (&net.Dialer{Timeout: 5 * time.Second}).DialContext(ctx, "tcp", addr)
-	if err != nil {
-		return 0, err
-	}
-	conn.Close()
-	return time.Since(start).Milliseconds(), nil
+// run runs script on the server the way the settings ask for: inside the
+// container if one is set, and through sudo unless disabled.
+func (a *NGINXAdapter) run(client *ssh.Client, script string, stdin io.Reader) (string, error) {
+	return runCommand(client, a.wrap(script), stdin)
 }
 
-func (a *NGINXAdapter) GetMetrics(ctx context.Context) (*models.ServerMetrics, error) {
-	client, err := a.getClient(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("ssh connect: %w", err)
+func (a *NGINXAdapter) wrap(script string) string {
+	return wrapCommand(script, a.settings.UseSudo == nil || *a.settings.UseSudo, a.settings.DockerContainer)
+}
+
+// nginx builds an nginx invocation, pointing it at the configured main
+// config file if there is one.
+func (a *NGINXAdapter) nginx(args string) string {
+	bin := a.settings.Binary
+	if bin == "" {
+		bin = "nginx"
 	}
-	session, err := client.NewSession()
-	if err != nil {
-		return nil, err
+	cmd := shellQuote(bin)
+	if a.settings.ConfigPath != "" {
+		cmd += " -c " + shellQuote(a.settings.ConfigPath)
 	}
-	defer session.Close()
+	return cmd + " " + args
+}
 
-	out, err := runSession(session, "curl -sf http://127.0.0.1/nginx_status 2>/dev/null || curl -sf http://127.0.0.1:8080/nginx_status 2>/dev/null || echo 'unavailable'")
-	if err != nil {
-		out = "unavailable"
+// testCommand tests a config; callers append -c with the file to test.
+func (a *NGINXAdapter) testCommand() string {
+	if a.settings.TestCommand != "" {
+		return a.settings.TestCommand
 	}
-
-	m := &models.ServerMetrics{
-		ServerID:  a.serverID,
-		Timestamp: time.Now(),
+	bin := a.settings.Binary
+	if bin == "" {
+		bin = "nginx"
 	}
-	parseNGINXStatus(out, m)
-	return m, nil
+	return shellQuote(bin) + " -t"
 }
 
-// parseNGINXStatus parses nginx stub_status output.
-// Active connections: 291
-// server accepts handled requests
-//
-//	16630948 16630948 31070465
-//
-// Reading: 6 Writing: 179 Waiting: 106
-func parseNGINXStatus(raw string, m *models.ServerMetrics) {
-	for _, line := range strings.Split(raw, "\n") {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "Active connections:") {
-			parts := strings.Fields(line)
-			if len(parts) >= 3 {
-				v, _ := strconv.Atoi(parts[2])
-				m.ActiveConnections = v
-			}
-		}
+func (a *NGINXAdapter) reloadCommand() string {
+	if a.settings.ReloadCommand != "" {
+		return a.settings.ReloadCommand
 	}
+	return a.nginx("-s reload")
 }
 
-func (a *NGINXAdapter) GetConfig(ctx context.Context) (*models.ProxyConfig, error) {
-	client, err := a.getClient(ctx)
-	if err != nil {
-		return nil, err
+func (a *NGINXAdapter) logPaths() (access, errorLog string) {
+	access, errorLog = a.settings.AccessLogPath, a.settings.ErrorLogPath
+	if access == "" {
+		access = "/var/log/nginx/access.log"
 	}
-	session, err := client.NewSession()
+	if errorLog == "" {
+		errorLog = "/var/log/nginx/error.log"
+	}
+	return access, errorLog
+}
+
+func (a *NGINXAdapter) Ping(ctx context.Context) (int64, error) {
+	start := time.Now()
+	addr := fmt.Sprintf("%s:%d", a.host, a.port)
+	conn, err := (&net.Dialer{Timeout: 5 * time.Second}).DialContext(ctx, "tcp", addr)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	defer session.Close()
+	conn.Close()
+	return time.Since(start).Milliseconds(), nil
+}
 
-	content, err := runSession(session, "cat /etc/nginx/nginx.conf 2>/dev/null || cat /usr/local/etc/nginx/nginx.conf 2>/dev/null")
+// GetConfig returns the main config as Content and, when `nginx -T` works,
+// every included file in Files.
+func (a *NGINXAdapter) GetConfig(ctx context.Context) (*models.ProxyConfig, error) {
+	client, err := a.getClient(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return &models.ProxyConfig{
+	cfg := &models.ProxyConfig{
 		ServerID:     a.serverID,
 		ServerName:   a.serverName,
 		ProxyType:    models.ProxyNGINX,
-		Content:      content,
 		Format:       "nginx",
 		LastModified: time.Now().Format(time.RFC3339),
 		IsValid:      true,
-	}, nil
-}
-
-func (a *NGINXAdapter) PutConfig(ctx context.Context, content string) (*models.ConfigValidation, error) {
-	client, err := a.getClient(ctx)
-	if err != nil {
-		return nil, err
 	}
 
-	escaped := strings.ReplaceAll(content, "'", "'\\''")
-	writeCmd := fmt.Sprintf("echo '%s' > /tmp/nginx_proxera.conf && nginx -t -c /tmp/nginx_proxera.conf 2>&1", escaped)
+	if files, err := a.configTree(client); err == nil {
+		cfg.Content = files[0].Content
+		cfg.Files = files
+		return cfg, nil
+	}
 
-	session, err := client.NewSession()
+	// nginx -T refuses an invalid config; fall back to the main file alone.
+	content, err := a.run(client, "cat "+shellQuote(a.mainConfigPath(client)), nil)
 	if err != nil {
 		return nil, err
 	}
-	defer session.Close()
-
-	out, err := runSession(session, writeCmd)
-	if err != nil || strings.Contains(out, "failed") || strings.Contains(out, "[emerg]") {
-		return &models.ConfigValidation{
-			IsValid: false,
-			Errors:  []string{out},
-		}, nil
-	}
+	cfg.Content = content
+	cfg.IsValid = false
+	cfg.ValidationErrors = []string{"nginx -T failed; showing the main config file only"}
+	return cfg, nil
+}
 
-	session2, err := client.NewSession()
+// PutConfig replaces the main config file; see PutConfigFiles.
+func (a *NGINXAdapter) PutConfig(ctx context.Context, content string) (*models.ConfigValidation, error) {
+	client, err := a.getClient(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer session2.Close()
-	runSession(session2, "sudo cp /tmp/nginx_proxera.conf /etc/nginx/nginx.conf") //nolint:errcheck
-
-	return &models.ConfigValidation{IsValid: true}, nil
+	return a.PutConfigFiles(ctx, []models.ConfigFile{{Path: a.mainConfigPath(client), Content: content}})
 }
 
 func (a *NGINXAdapter) Reload(ctx context.Context) error {
@@ -163,47 +157,24 @@ func (a *NGINXAdapter) Reload(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	session, err := client.NewSession()
-	if err != nil {
-		return err
+	if out, err := a.run(client, a.reloadCommand(), nil); err != nil {
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(out))
 	}
-	defer session.Close()
-	_, err = runSession(session, "sudo nginx -s reload")
-	return err
+	return nil
 }
 
+// TailLogs follows the configured log source, or by default the access
+// and error logs.
 func (a *NGINXAdapter) TailLogs(ctx context.Context) (io.ReadCloser, error) {
-	client, err := a.getClient(ctx)
-	if err != nil {
-		return nil, err
+	if a.logs != nil {
+		return a.logs.tail(ctx)
 	}
-	session, err := client.NewSession()
+	client, err := a.getClient(ctx)
 	if err != nil {
 		return nil, err
 	}
-
-	pr, pw := io.Pipe()
-	session.Stdout = pw
-	session.Stderr = pw
-
-	if err := session.Start("tail -F /var/log/nginx/access.log /var/log/nginx/error.log 2>/dev/null"); err != nil {
-		session.Close()
-		pw.Close()
-		return nil, err
-	}
-
-	go func() {
-		<-ctx.Done()
-		session.Close()
-		pw.Close()
-	}()
-
-	go func() {
-		session.Wait() //nolint:errcheck
-		pw.Close()
-	}()
-
-	return pr, nil
+	access, errorLog := a.logPaths()
+	return streamCommand(ctx, client, a.wrap("tail -F "+shellQuote(access)+" "+shellQuote(errorLog)+" 2>/dev/null"))
 }
 
 func (a *NGINXAdapter) GetStatus(ctx context.Context) (string, error) {
@@ -0,0 +1,267 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/anveesa/proxera/models"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	defaultNGINXConf = "/etc/nginx/nginx.conf"
+
+	nginxDumpMarker = "# configuration file "
+
+	// nginxStateDir is the directory, inside the main config's directory,
+	// where PutConfigFiles stages new files and keeps backups. Being on the
+	// same filesystem as the config makes the final rename atomic, and
+	// nginx's include globs skip dot entries, so it is never loaded.
+	nginxStateDir = ".proxera"
+)
+
+// configTree returns every file nginx loads, main config first, from the
+// dump printed by `nginx -T`. It fails if the current config is invalid.
+func (a *NGINXAdapter) configTree(client *ssh.Client) ([]models.ConfigFile, error) {
+	out, err := a.run(client, a.nginx("-T 2>/dev/null"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("nginx -T: %w", err)
+	}
+	files := parseNGINXDump(out)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("nginx -T printed no configuration")
+	}
+	return files, nil
+}
+
+// parseNGINXDump splits `nginx -T` output into files. Each file is printed
+// as "# configuration file <path>:" followed by its content and one extra
+// newline.
+func parseNGINXDump(out string) []models.ConfigFile {
+	var files []models.ConfigFile
+	var cur *models.ConfigFile
+	var body strings.Builder
+
+	flush := func() {
+		if cur == nil {
+			return
+		}
+		cur.Content = strings.TrimSuffix(body.String(), "\n")
+		files = append(files, *cur)
+		body.Reset()
+	}
+
+	for _, line := range strings.SplitAfter(out, "\n") {
+		trimmed := strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(trimmed, nginxDumpMarker) && strings.HasSuffix(trimmed, ":") {
+			flush()
+			p := strings.TrimSuffix(strings.TrimPrefix(trimmed, nginxDumpMarker), ":")
+			cur = &models.ConfigFile{Path: p}
+			continue
+		}
+		if cur != nil {
+			body.WriteString(line)
+		}
+	}
+	flush()
+	return files
+}
+
+// mainConfigPath returns the configured main config path, or else asks the
+// nginx binary for its compiled-in one, which works even when the current
+// config does not parse.
+func (a *NGINXAdapter) mainConfigPath(client *ssh.Client) string {
+	if a.settings.ConfigPath != "" {
+		return a.settings.ConfigPath
+	}
+	out, err := a.run(client, a.nginx("-V 2>&1"), nil)
+	if err != nil {
+		return defaultNGINXConf
+	}
+	for _, field := range strings.Fields(out) {
+		if p, ok := strings.CutPrefix(field, "--conf-path="); ok {
+			return p
+		}
+	}
+	return defaultNGINXConf
+}
+
+// PutConfigFiles stages each file in a work directory under nginxStateDir
+// and tests the result before anything live changes: the config directory
+// is copied into the work directory with the files applied, and `nginx -t`
+// is run against that copy. Only if it passes are the files renamed into
+// place, taking a backup of each file they replace or delete. Nothing is
+// ever written beside the live files, as `include sites-enabled/*;` would
+// load it. If a rename fails, every file is put back as it was; any
+// failure is returned in the validation result. On success the previous
+// version of each file is kept under nginxStateDir/backup, at its path
+// relative to the config directory. nginx itself is not reloaded.
+func (a *NGINXAdapter) PutConfigFiles(ctx context.Context, files []models.ConfigFile) (*models.ConfigValidation, error) {
+	client, err := a.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Files may go anywhere under the main config's directory, which keeps
+	// them on the staging directory's filesystem and inside the copy that
+	// is tested.
+	mainPath := a.mainConfigPath(client)
+	root := path.Dir(mainPath)
+	state := root + "/" + nginxStateDir
+
+	var errs []string
+	for _, f := range files {
+		if !path.IsAbs(f.Path) || path.Clean(f.Path) != f.Path {
+			errs = append(errs, fmt.Sprintf("%s: path must be absolute and clean", f.Path))
+		} else if !strings.HasPrefix(f.Path, root+"/") {
+			errs = append(errs, fmt.Sprintf("%s: outside %s", f.Path, root))
+		} else if f.Path == state || strings.HasPrefix(f.Path, state+"/") {
+			errs = append(errs, fmt.Sprintf("%s: inside Proxera's staging directory", f.Path))
+		}
+	}
+	if len(errs) > 0 {
+		return invalid(errs...), nil
+	}
+
+	out, err := a.run(client, fmt.Sprintf("mkdir -p -m 0700 %[1]s && mktemp -d %[1]s/work-XXXXXX", shellQuote(state)), nil)
+	work := strings.TrimSpace(out)
+	if err != nil || !strings.HasPrefix(work, state+"/work-") {
+		return invalid("create work directory: " + commandError(out, err)), nil
+	}
+	defer a.run(client, "rm -rf "+shellQuote(work), nil) //nolint:errcheck
+
+	writes := make([]nginxWrite, len(files))
+	for i, f := range files {
+		writes[i] = nginxWrite{ConfigFile: f, backup: fmt.Sprintf("%s/%d.bak", work, i)}
+		if f.Delete {
+			continue
+		}
+		tmpl := fmt.Sprintf("%s/%d.new-XXXXXX", work, i)
+		out, err := a.run(client, stageScript(f.Path, tmpl), strings.NewReader(f.Content))
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		writes[i].staged = lines[len(lines)-1]
+		if err != nil || !strings.HasPrefix(writes[i].staged, fmt.Sprintf("%s/%d.new-", work, i)) {
+			return invalid(fmt.Sprintf("upload %s: %s", f.Path, commandError(out, err))), nil
+		}
+	}
+
+	tree := work + "/tree"
+	if out, err := a.run(client, stageTreeScript(root, tree, writes), nil); err != nil {
+		return invalid("copy config for testing: " + commandError(out, err)), nil
+	}
+	out, err = a.run(client, a.testCommand()+" -c "+shellQuote(tree+strings.TrimPrefix(mainPath, root))+" 2>&1", nil)
+	if err != nil || strings.Contains(out, "[emerg]") {
+		return invalid(strings.ReplaceAll(strings.TrimSpace(out), tree, root)), nil
+	}
+
+	var swapped []nginxWrite
+	for _, w := range writes {
+		p, bak := shellQuote(w.Path), shellQuote(w.backup)
+		var cmd string
+		if w.Delete {
+			cmd = fmt.Sprintf("if [ -e %[1]s ]; then mv -f %[1]s %[2]s; fi", p, bak)
+		} else {
+			cmd = fmt.Sprintf("if [ -e %[1]s ]; then cp -p %[1]s %[2]s; fi && mv -f %[3]s %[1]s",
+				p, bak, shellQuote(w.staged))
+		}
+		if out, err := a.run(client, cmd, nil); err != nil {
+			errs = append(errs, fmt.Sprintf("install %s: %s", w.Path, commandError(out, err)))
+			return invalid(append(errs, a.restore(client, swapped)...)...), nil
+		}
+		swapped = append(swapped, w)
+	}
+	a.keepBackups(client, state, root, writes)
+	return &models.ConfigValidation{IsValid: true}, nil
+}
+
+// stageTreeScript copies the config directory root, less nginxStateDir,
+// to tree and applies writes to the copy. Absolute includes and symlinks
+// that point into root are redirected into tree, so the copy loads none of
+// the live files it replaces.
+func stageTreeScript(root, tree string, writes []nginxWrite) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "set -e; r=%[1]s; d=%[2]s; mkdir \"$d\"; tar -C \"$r\" --exclude=./%[3]s -cf - . | tar -C \"$d\" -xpf -; ",
+		shellQuote(root), shellQuote(tree), nginxStateDir)
+	for _, w := range writes {
+		dest := shellQuote(tree + strings.TrimPrefix(w.Path, root))
+		if w.Delete {
+			fmt.Fprintf(&b, "rm -f %s; ", dest)
+		} else {
+			fmt.Fprintf(&b, "mkdir -p %s; rm -f %s; cp -p %s %s; ",
+				shellQuote(path.Dir(tree+strings.TrimPrefix(w.Path, root))), dest, shellQuote(w.staged), dest)
+		}
+	}
+	b.WriteString(`find "$d" -type l | while IFS= read -r l; do t=$(readlink "$l"); ` +
+		`case "$t" in "$r"/*) ln -sfn "$d/${t#"$r"/}" "$l";; esac; done; `)
+	fmt.Fprintf(&b, `find "$d" -type f -exec sed -i -E %s {} +; `, shellQuote(
+		`s#(^|[;{[:space:]])(include[[:space:]]+["']?)`+sedPattern(root)+`/#\1\2`+sedReplacement(tree)+`/#g`))
+	return b.String()
+}
+
+// sedPattern escapes s for use in an extended sed regex delimited by #.
+func sedPattern(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`\.[]*^$+?(){}|#`, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// sedReplacement escapes s for use in a sed replacement delimited by #.
+func sedReplacement(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`\&#`, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// nginxWrite is one file of a PutConfigFiles call with where it is staged
+// and where its previous version is kept.
+type nginxWrite struct {
+	models.ConfigFile
+	staged string
+	backup string
+}
+
+// restore puts back the backups taken for writes, removing files that did
+// not exist before. It returns a message for each file it could not
+// restore.
+func (a *NGINXAdapter) restore(client *ssh.Client, writes []nginxWrite) []string {
+	var errs []string
+	for _, w := range writes {
+		p, bak := shellQuote(w.Path), shellQuote(w.backup)
+		cmd := fmt.Sprintf("if [ -e %[2]s ]; then mv -f %[2]s %[1]s; else rm -f %[1]s; fi", p, bak)
+		if out, err := a.run(client, cmd, nil); err != nil {
+			errs = append(errs, fmt.Sprintf("restore %s: %s", w.Path, commandError(out, err)))
+		}
+	}
+	return errs
+}
+
+// keepBackups moves the backups taken for writes to nginxStateDir/backup,
+// replacing those of earlier writes. The new config is already in place,
+// so failures are only logged.
+func (a *NGINXAdapter) keepBackups(client *ssh.Client, state, root string, writes []nginxWrite) {
+	for _, w := range writes {
+		dest := state + "/backup/" + strings.TrimPrefix(w.Path, root+"/")
+		cmd := fmt.Sprintf("if [ -e %[1]s ]; then mkdir -p %[3]s && mv -f %[1]s %[2]s; fi",
+			shellQuote(w.backup), shellQuote(dest), shellQuote(path.Dir(dest)))
+		if out, err := a.run(client, cmd, nil); err != nil {
+			log.Printf("NGINX %s: keep backup of %s: %s", a.serverID, w.Path, commandError(out, err))
+		}
+	}
+}
+
+func invalid(errs ...string) *models.ConfigValidation {
+	return &models.ConfigValidation{IsValid: false, Errors: errs}
+}
@@ -0,0 +1,220 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anveesa/proxera/models"
+	"golang.org/x/crypto/ssh"
+)
+
+// metricsSection starts each part of the GetMetrics output after the
+// stub_status page.
+const metricsSection = "--- proxera:"
+
+// GetMetrics reads stub_status and the /proc counters in one SSH command,
+// inside the container if one is set. Requests per second, CPU usage and
+// network rates are diffed against the previous sample for this server, so
+// the first sample has no rates and reports CPU usage since boot.
+func (a *NGINXAdapter) GetMetrics(ctx context.Context) (*models.ServerMetrics, error) {
+	client, err := a.getClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ssh connect: %w", err)
+	}
+	cmd := "curl -sf http://127.0.0.1/nginx_status 2>/dev/null || curl -sf http://127.0.0.1:8080/nginx_status 2>/dev/null || echo 'unavailable'"
+	if u := a.settings.StatusURL; u != "" {
+		cmd = "curl -sf " + shellQuote(u) + " 2>/dev/null || echo 'unavailable'"
+	}
+	cmd += "; echo '" + metricsSection + "stat'; head -n1 /proc/stat" +
+		"; echo '" + metricsSection + "meminfo'; cat /proc/meminfo" +
+		"; echo '" + metricsSection + "netdev'; cat /proc/net/dev"
+	// Run the way every other command is, so a containerised nginx is
+	// reached on its own loopback. A part that fails leaves its section
+	// empty and the rest still counts, so only a failure to run the
+	// command at all is an error here.
+	out, err := a.run(client, cmd, nil)
+	var exitErr *ssh.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, fmt.Errorf("read metrics: %w", err)
+	}
+
+	return nginxMetrics(a.serverID, splitMetricsSections(out))
+}
+
+// splitMetricsSections splits GetMetrics output by section name; the
+// stub_status page comes first, under "".
+func splitMetricsSections(out string) map[string]string {
+	sections := map[string]string{}
+	name := ""
+	var body strings.Builder
+	for _, line := range strings.Split(out, "\n") {
+		if next, ok := strings.CutPrefix(line, metricsSection); ok {
+			sections[name] = body.String()
+			body.Reset()
+			name = strings.TrimSpace(next)
+			continue
+		}
+		body.WriteString(line)
+		body.WriteByte('\n')
+	}
+	sections[name] = body.String()
+	return sections
+}
+
+// nginxMetrics builds a sample from whichever sections parse, and fails if
+// none does.
+func nginxMetrics(serverID string, sections map[string]string) (*models.ServerMetrics, error) {
+	m := &models.ServerMetrics{
+		ServerID:  serverID,
+		Timestamp: time.Now(),
+	}
+
+	counters := map[string]float64{}
+	if parseNGINXStatus(sections[""], m) {
+		counters["requests"] = float64(m.Connections.Requests)
+	}
+	cpuTotal, cpuIdle, haveCPU := parseProcStat(sections["stat"])
+	if haveCPU {
+		counters["cpu/total"], counters["cpu/idle"] = cpuTotal, cpuIdle
+	}
+	total, available, haveMem := parseMeminfo(sections["meminfo"])
+	if haveMem {
+		m.MemUsage = (total - available) / total * 100
+	}
+	if rx, tx, ok := parseNetDev(sections["netdev"]); ok {
+		counters["net/rx"], counters["net/tx"] = rx, tx
+	}
+	if len(counters) == 0 && !haveMem {
+		return nil, fmt.Errorf("no metrics: stub_status and /proc could not be read")
+	}
+
+	deltas, seconds, ok := counterDeltas(serverID, counters)
+	m.Cumulative = !ok
+	if ok {
+		m.RequestsPerSec = deltas["requests"] / seconds
+		m.NetworkIn = deltas["net/rx"] / seconds
+		m.NetworkOut = deltas["net/tx"] / seconds
+		cpuTotal, cpuIdle = deltas["cpu/total"], deltas["cpu/idle"]
+	}
+	if haveCPU && cpuTotal > 0 {
+		m.CPUUsage = (cpuTotal - cpuIdle) / cpuTotal * 100
+	}
+	return m, nil
+}
+
+// parseNGINXStatus parses nginx stub_status output into m and reports
+// whether it was there:
+//
+//	Active connections: 291
+//	server accepts handled requests
+//	 16630948 16630948 31070465
+//	Reading: 6 Writing: 179 Waiting: 106
+func parseNGINXStatus(raw string, m *models.ServerMetrics) bool {
+	var st models.ConnectionStats
+	found := false
+	lines := strings.Split(raw, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		fields := strings.Fields(line)
+		switch {
+		case strings.HasPrefix(line, "Active connections:") && len(fields) >= 3:
+			m.ActiveConnections, _ = strconv.Atoi(fields[2])
+			found = true
+		case strings.HasPrefix(line, "server accepts handled requests") && i+1 < len(lines):
+			counts := strings.Fields(lines[i+1])
+			if len(counts) >= 3 {
+				st.Accepted, _ = strconv.ParseInt(counts[0], 10, 64)
+				st.Handled, _ = strconv.ParseInt(counts[1], 10, 64)
+				st.Requests, _ = strconv.ParseInt(counts[2], 10, 64)
+				st.Dropped = st.Accepted - st.Handled
+			}
+		case strings.HasPrefix(line, "Reading:"):
+			for j := 0; j+1 < len(fields); j += 2 {
+				v, _ := strconv.Atoi(fields[j+1])
+				switch fields[j] {
+				case "Reading:":
+					st.Reading = v
+				case "Writing:":
+					st.Writing = v
+				case "Waiting:":
+					st.Waiting = v
+				}
+			}
+		}
+	}
+	if found {
+		m.Connections = &st
+	}
+	return found
+}
+
+// parseProcStat returns the total and idle (idle + iowait) jiffies from
+// the "cpu" line of /proc/stat.
+func parseProcStat(raw string) (total, idle float64, ok bool) {
+	fields := strings.Fields(raw)
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return 0, 0, false
+	}
+	// user nice system idle iowait irq softirq steal guest guest_nice;
+	// guest time is already counted in user and nice.
+	for i, f := range fields[1:] {
+		if i >= 8 {
+			break
+		}
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		total += v
+		if i == 3 || i == 4 {
+			idle += v
+		}
+	}
+	return total, idle, true
+}
+
+// parseMeminfo returns MemTotal and MemAvailable from /proc/meminfo.
+func parseMeminfo(raw string) (total, available float64, ok bool) {
+	for _, line := range strings.Split(raw, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		v, _ := strconv.ParseFloat(fields[1], 64)
+		switch fields[0] {
+		case "MemTotal:":
+			total = v
+		case "MemAvailable:":
+			available = v
+		}
+	}
+	return total, available, total > 0
+}
+
+// parseNetDev sums received and sent bytes over every interface in
+// /proc/net/dev except loopback.
+func parseNetDev(raw string) (rx, tx float64, ok bool) {
+	for _, line := range strings.Split(raw, "\n") {
+		iface, stats, found := strings.Cut(line, ":")
+		if !found || strings.TrimSpace(iface) == "lo" {
+			continue
+		}
+		fields := strings.Fields(stats)
+		if len(fields) < 9 {
+			continue
+		}
+		r, err1 := strconv.ParseFloat(fields[0], 64)
+		t, err2 := strconv.ParseFloat(fields[8], 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		rx += r
+		tx += t
+		ok = true
+	}
+	return rx, tx, ok
+}
@@ -0,0 +1,245 @@
+package proxy
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anveesa/proxera/models"
+)
+
+const (
+	stubStatusFixture = `Active connections: 291
+server accepts handled requests
+ 16630948 16630940 31070465
+Reading: 6 Writing: 179 Waiting: 106
+`
+	procStatFixture = "cpu  4705 150 1120 16250 520 30 45 10 5 0\n"
+
+	meminfoFixture = `MemTotal:        8000000 kB
+MemFree:          500000 kB
+MemAvailable:    2000000 kB
+Buffers:          100000 kB
+Cached:          1200000 kB
+SwapTotal:             0 kB
+`
+	netDevFixture = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo: 99999999  100000    0    0    0     0          0         0 99999999  100000    0    0    0     0       0          0
+  eth0: 1000000    8000    0    0    0     0          0         0   400000    5000    0    0    0     0       0          0
+docker0:   20000     100    0    0    0     0          0         0    30000     120    0    0    0     0       0          0
+`
+)
+
+func TestParseNGINXStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		wantFound  bool
+		wantActive int
+		wantStats  *models.ConnectionStats
+	}{
+		{"full", stubStatusFixture, true, 291, &models.ConnectionStats{
+			Accepted: 16630948, Handled: 16630940, Requests: 31070465, Dropped: 8,
+			Reading: 6, Writing: 179, Waiting: 106,
+		}},
+		{"nothing dropped", "Active connections: 1 \nserver accepts handled requests\n 5 5 9 \nReading: 0 Writing: 1 Waiting: 0 \n", true, 1,
+			&models.ConnectionStats{Accepted: 5, Handled: 5, Requests: 9, Writing: 1}},
+		{"counts missing", "Active connections: 3\nserver accepts handled requests\n", true, 3, &models.ConnectionStats{}},
+		{"curl failed", "unavailable\n", false, 0, nil},
+		{"html page", "<html><body>Welcome to nginx!</body></html>\n", false, 0, nil},
+		{"empty", "", false, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m models.ServerMetrics
+			if found := parseNGINXStatus(tt.raw, &m); found != tt.wantFound {
+				t.Fatalf("parseNGINXStatus() = %t, want %t", found, tt.wantFound)
+			}
+			if m.ActiveConnections != tt.wantActive {
+				t.Errorf("ActiveConnections = %d, want %d", m.ActiveConnections, tt.wantActive)
+			}
+			if !reflect.DeepEqual(m.Connections, tt.wantStats) {
+				t.Errorf("Connections = %+v, want %+v", m.Connections, tt.wantStats)
+			}
+		})
+	}
+}
+
+func TestParseProcStat(t *testing.T) {
+	tests := []struct {
+		name                string
+		raw                 string
+		wantTotal, wantIdle float64
+		wantOK              bool
+	}{
+		// guest and guest_nice are left out, being counted in user and nice.
+		{"kernel 2.6.33+", procStatFixture, 22830, 16770, true},
+		{"four fields", "cpu 100 0 50 850", 1000, 850, true},
+		{"per-cpu line", "cpu0 100 0 50 850 0", 0, 0, false},
+		{"too short", "cpu 100 0 50", 0, 0, false},
+		{"not a number", "cpu 100 x 50 850 0", 0, 0, false},
+		{"empty", "", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, idle, ok := parseProcStat(tt.raw)
+			if total != tt.wantTotal || idle != tt.wantIdle || ok != tt.wantOK {
+				t.Errorf("parseProcStat() = %v, %v, %t, want %v, %v, %t", total, idle, ok, tt.wantTotal, tt.wantIdle, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseMeminfo(t *testing.T) {
+	tests := []struct {
+		name                     string
+		raw                      string
+		wantTotal, wantAvailable float64
+		wantOK                   bool
+	}{
+		{"meminfo", meminfoFixture, 8000000, 2000000, true},
+		{"no MemAvailable", "MemTotal: 1000 kB\nMemFree: 200 kB\n", 1000, 0, true},
+		{"no MemTotal", "MemAvailable: 1000 kB\n", 0, 1000, false},
+		{"empty", "", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, available, ok := parseMeminfo(tt.raw)
+			if total != tt.wantTotal || available != tt.wantAvailable || ok != tt.wantOK {
+				t.Errorf("parseMeminfo() = %v, %v, %t, want %v, %v, %t",
+					total, available, ok, tt.wantTotal, tt.wantAvailable, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseNetDev(t *testing.T) {
+	tests := []struct {
+		name           string
+		raw            string
+		wantRx, wantTx float64
+		wantOK         bool
+	}{
+		{"loopback left out", netDevFixture, 1020000, 430000, true},
+		{"loopback only", "  lo: 10 1 0 0 0 0 0 0 10 1 0 0 0 0 0 0\n", 0, 0, false},
+		{"short line", "eth0: 10 1 0 0\n", 0, 0, false},
+		{"headers only", strings.Join(strings.Split(netDevFixture, "\n")[:2], "\n"), 0, 0, false},
+		{"empty", "", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rx, tx, ok := parseNetDev(tt.raw)
+			if rx != tt.wantRx || tx != tt.wantTx || ok != tt.wantOK {
+				t.Errorf("parseNetDev() = %v, %v, %t, want %v, %v, %t", rx, tx, ok, tt.wantRx, tt.wantTx, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSplitMetricsSections(t *testing.T) {
+	out := stubStatusFixture +
+		metricsSection + "stat\n" + procStatFixture +
+		metricsSection + "meminfo\n" +
+		metricsSection + "netdev\n" + netDevFixture
+	sections := splitMetricsSections(out)
+	want := map[string]string{
+		"":        stubStatusFixture,
+		"stat":    procStatFixture,
+		"meminfo": "",
+		"netdev":  netDevFixture + "\n",
+	}
+	if !reflect.DeepEqual(sections, want) {
+		t.Errorf("splitMetricsSections() = %q, want %q", sections, want)
+	}
+}
+
+func TestNGINXMetrics(t *testing.T) {
+	const id = "nginx-metrics-test"
+	defer ForgetCounters(id)
+	sections := map[string]string{
+		"":        stubStatusFixture,
+		"stat":    procStatFixture,
+		"meminfo": meminfoFixture,
+		"netdev":  netDevFixture,
+	}
+
+	m, err := nginxMetrics(id, sections)
+	if err != nil {
+		t.Fatalf("nginxMetrics() error = %v", err)
+	}
+	if !m.Cumulative || m.RequestsPerSec != 0 || m.NetworkIn != 0 {
+		t.Errorf("first sample: Cumulative = %t, RequestsPerSec = %v, NetworkIn = %v, want true, 0, 0",
+			m.Cumulative, m.RequestsPerSec, m.NetworkIn)
+	}
+	if m.MemUsage != 75 {
+		t.Errorf("MemUsage = %v, want 75", m.MemUsage)
+	}
+	if want := (22830.0 - 16770) / 22830 * 100; m.CPUUsage != want {
+		t.Errorf("CPUUsage since boot = %v, want %v", m.CPUUsage, want)
+	}
+	if m.Connections == nil || m.Connections.Dropped != 8 {
+		t.Errorf("Connections = %+v, want 8 dropped", m.Connections)
+	}
+
+	// Ten seconds later: 1000 more requests, 200 jiffies of which 50 idle,
+	// 50000 bytes in and 20000 out.
+	counterSamples.Lock()
+	s := counterSamples.m[id]
+	s.at = s.at.Add(-10 * time.Second)
+	counterSamples.m[id] = s
+	counterSamples.Unlock()
+
+	sections[""] = strings.Replace(stubStatusFixture, "31070465", "31071465", 1)
+	sections["stat"] = "cpu  4855 150 1120 16300 520 30 45 10 5 0\n"
+	sections["netdev"] = strings.Replace(strings.Replace(netDevFixture, "1000000", "1050000", 1), "400000", "420000", 1)
+	m, err = nginxMetrics(id, sections)
+	if err != nil {
+		t.Fatalf("nginxMetrics() error = %v", err)
+	}
+	if m.Cumulative {
+		t.Error("second sample is Cumulative")
+	}
+	for _, c := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"RequestsPerSec", m.RequestsPerSec, 100},
+		{"NetworkIn", m.NetworkIn, 5000},
+		{"NetworkOut", m.NetworkOut, 2000},
+		{"CPUUsage", m.CPUUsage, 75},
+	} {
+		if math.Abs(c.got-c.want)/c.want > 0.05 {
+			t.Errorf("%s = %v, want about %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestNGINXMetricsPartial(t *testing.T) {
+	tests := []struct {
+		name     string
+		sections map[string]string
+		wantErr  bool
+	}{
+		{"stub_status only", map[string]string{"": stubStatusFixture}, false},
+		{"proc only", map[string]string{"": "unavailable\n", "stat": procStatFixture, "meminfo": meminfoFixture}, false},
+		{"meminfo only", map[string]string{"meminfo": meminfoFixture}, false},
+		{"nothing", map[string]string{"": "unavailable\n", "stat": "", "meminfo": "", "netdev": ""}, true},
+		{"no output", map[string]string{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := "nginx-metrics-partial-" + tt.name
+			defer ForgetCounters(id)
+			m, err := nginxMetrics(id, tt.sections)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("nginxMetrics() error = %v, want error %t", err, tt.wantErr)
+			}
+			if err == nil && m == nil {
+				t.Fatal("nginxMetrics() returned no sample and no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package proxy
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/anveesa/proxera/models"
+)
+
+// promSample is one sample of a Prometheus text-format exposition.
+type promSample struct {
+	labels map[string]string
+	value  float64
+}
+
+// promMetrics holds the samples of a scrape by metric name, as written:
+// a histogram shows up as its _bucket, _sum and _count series.
+type promMetrics map[string][]promSample
+
+// parsePrometheus parses the Prometheus text exposition format. Comments,
+// HELP/TYPE lines and timestamps are ignored.
+func parsePrometheus(text string) (promMetrics, error) {
+	metrics := promMetrics{}
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		name, s, err := parsePromLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+		metrics[name] = append(metrics[name], s)
+	}
+	return metrics, nil
+}
+
+func parsePromLine(line string) (string, promSample, error) {
+	s := promSample{labels: map[string]string{}}
+	end := strings.IndexAny(line, "{ \t")
+	if end <= 0 {
+		return "", s, fmt.Errorf("no value in %q", line)
+	}
+	name, rest := line[:end], line[end:]
+
+	if rest[0] == '{' {
+		rest = rest[1:]
+		for {
+			rest = strings.TrimLeft(rest, " \t,")
+			if rest == "" {
+				return "", s, fmt.Errorf("unterminated labels in %q", line)
+			}
+			if rest[0] == '}' {
+				rest = rest[1:]
+				break
+			}
+			eq := strings.IndexByte(rest, '=')
+			if eq <= 0 || eq+1 >= len(rest) || rest[eq+1] != '"' {
+				return "", s, fmt.Errorf("malformed label in %q", line)
+			}
+			key := strings.TrimSpace(rest[:eq])
+			value, n, err := unquotePromLabel(rest[eq+2:])
+			if err != nil {
+				return "", s, fmt.Errorf("%w in %q", err, line)
+			}
+			s.labels[key] = value
+			rest = rest[eq+2+n:]
+		}
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", s, fmt.Errorf("no value in %q", line)
+	}
+	v, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", s, fmt.Errorf("bad value in %q", line)
+	}
+	s.value = v
+	return name, s, nil
+}
+
+// unquotePromLabel reads a label value up to its closing quote and returns
+// it with the number of bytes consumed, quote included.
+func unquotePromLabel(s string) (string, int, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			return b.String(), i + 1, nil
+		case '\\':
+			if i+1 == len(s) {
+				break
+			}
+			i++
+			if s[i] == 'n' {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated label value")
+}
+
+// sum adds up the samples of name whose labels pass keep (all if nil).
+func (p promMetrics) sum(name string, keep func(labels map[string]string) bool) float64 {
+	var total float64
+	for _, s := range p[name] {
+		if keep == nil || keep(s.labels) {
+			total += s.value
+		}
+	}
+	return total
+}
+
+// buckets merges the cumulative bucket counts of histogram name across the
+// label sets that pass keep, keyed by upper bound.
+func (p promMetrics) buckets(name string, keep func(labels map[string]string) bool) map[float64]float64 {
+	out := map[float64]float64{}
+	for _, s := range p[name+"_bucket"] {
+		if keep != nil && !keep(s.labels) {
+			continue
+		}
+		le, err := strconv.ParseFloat(s.labels["le"], 64)
+		if err != nil {
+			continue
+		}
+		out[le] += s.value
+	}
+	return out
+}
+
+// addBuckets stores histogram buckets under prefix in a counter map, so
+// they can be diffed between scrapes like any other counter.
+func addBuckets(counters map[string]float64, prefix string, buckets map[float64]float64) {
+	for le, v := range buckets {
+		counters[prefix+strconv.FormatFloat(le, 'g', -1, 64)] = v
+	}
+}
+
+// bucketsFrom is the reverse of addBuckets.
+func bucketsFrom(counters map[string]float64, prefix string) map[float64]float64 {
+	out := map[float64]float64{}
+	for k, v := range counters {
+		if rest, ok := strings.CutPrefix(k, prefix); ok {
+			if le, err := strconv.ParseFloat(rest, 64); err == nil {
+				out[le] = v
+			}
+		}
+	}
+	return out
+}
+
+// histogramQuantile estimates the q-quantile from cumulative bucket
+// counts by linear interpolation inside the bucket it falls in, the way
+// Prometheus' histogram_quantile does. It returns 0 for an empty histogram.
+func histogramQuantile(q float64, buckets map[float64]float64) float64 {
+	bounds := make([]float64, 0, len(buckets))
+	for le := range buckets {
+		bounds = append(bounds, le)
+	}
+	sort.Float64s(bounds)
+	if len(bounds) == 0 {
+		return 0
+	}
+	total := buckets[bounds[len(bounds)-1]]
+	if total <= 0 {
+		return 0
+	}
+
+	rank := q * total
+	var lower, below float64
+	for i, le := range bounds {
+		count := buckets[le]
+		if count >= rank {
+			if math.IsInf(le, 1) {
+				// Past the last finite bound; that bound is the best guess.
+				if i == 0 {
+					return 0
+				}
+				return bounds[i-1]
+			}
+			if count == below {
+				return le
+			}
+			return lower + (le-lower)*(rank-below)/(count-below)
+		}
+		lower, below = le, count
+	}
+	return bounds[len(bounds)-1]
+}
+
+// setLatencyPercentiles fills P50/P95/P99 in milliseconds from the buckets
+// of a histogram measured in seconds.
+func setLatencyPercentiles(m *models.ServerMetrics, buckets map[float64]float64) {
+	m.P50Latency = histogramQuantile(0.50, buckets) * 1000
+	m.P95Latency = histogramQuantile(0.95, buckets) * 1000
+	m.P99Latency = histogramQuantile(0.99, buckets) * 1000
+}
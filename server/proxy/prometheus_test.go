@@ -0,0 +1,212 @@
+package proxy
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePromLine(t *testing.T) {
+	tests := []struct {
+		name       string
+		line       string
+		wantName   string
+		wantLabels map[string]string
+		wantValue  float64
+		wantErr    string
+	}{
+		{"no labels", "process_open_fds 12", "process_open_fds", map[string]string{}, 12, ""},
+		{"labels", `http_requests_total{code="200",method="GET"} 1027`, "http_requests_total",
+			map[string]string{"code": "200", "method": "GET"}, 1027, ""},
+		{"trailing comma", `m{a="1",} 2`, "m", map[string]string{"a": "1"}, 2, ""},
+		{"spaces in labels", `m{ a="1" , b="2" } 3`, "m", map[string]string{"a": "1", "b": "2"}, 3, ""},
+		{"empty labels", `m{} 4`, "m", map[string]string{}, 4, ""},
+		{"escaped label", `m{path="C:\\dir\\\"x\"\nend"} 1`, "m",
+			map[string]string{"path": "C:\\dir\\\"x\"\nend"}, 1, ""},
+		{"brace and comma in label", `m{rule="Host(` + "`a.com`" + `) {x,y}"} 1`, "m",
+			map[string]string{"rule": "Host(`a.com`) {x,y}"}, 1, ""},
+		{"scientific value", "m 1.5e+06", "m", map[string]string{}, 1.5e6, ""},
+		{"+Inf value", `m_bucket{le="+Inf"} +Inf`, "m_bucket", map[string]string{"le": "+Inf"}, math.Inf(1), ""},
+		{"-Inf value", "m -Inf", "m", map[string]string{}, math.Inf(-1), ""},
+		{"timestamp", "m 3 1395066363000", "m", map[string]string{}, 3, ""},
+		{"labels and timestamp", `m{a="1"} 3 1395066363000`, "m", map[string]string{"a": "1"}, 3, ""},
+
+		{"no value", "m", "", nil, 0, "no value"},
+		{"no value after labels", `m{a="1"}`, "", nil, 0, "no value"},
+		{"bad value", "m abc", "", nil, 0, "bad value"},
+		{"unterminated labels", `m{a="1"`, "", nil, 0, "unterminated labels"},
+		{"missing closing brace", `m{a="1" 2`, "", nil, 0, "malformed label"},
+		{"unterminated label value", `m{a="1} 2`, "", nil, 0, "unterminated label value"},
+		{"unquoted label value", `m{a=1} 2`, "", nil, 0, "malformed label"},
+		{"label without name", `m{="1"} 2`, "", nil, 0, "malformed label"},
+		{"leading brace", `{a="1"} 2`, "", nil, 0, "no value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, s, err := parsePromLine(tt.line)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parsePromLine(%q) error = %v, want error containing %q", tt.line, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePromLine(%q) error = %v", tt.line, err)
+			}
+			if name != tt.wantName {
+				t.Errorf("name = %q, want %q", name, tt.wantName)
+			}
+			if !reflect.DeepEqual(s.labels, tt.wantLabels) {
+				t.Errorf("labels = %q, want %q", s.labels, tt.wantLabels)
+			}
+			if s.value != tt.wantValue {
+				t.Errorf("value = %v, want %v", s.value, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestParsePromLineNaN(t *testing.T) {
+	_, s, err := parsePromLine("m NaN")
+	if err != nil {
+		t.Fatalf("parsePromLine() error = %v", err)
+	}
+	if !math.IsNaN(s.value) {
+		t.Errorf("value = %v, want NaN", s.value)
+	}
+}
+
+func TestParsePrometheus(t *testing.T) {
+	text := `# HELP caddy_http_request_duration_seconds Histogram of round-trip request durations.
+# TYPE caddy_http_request_duration_seconds histogram
+caddy_http_request_duration_seconds_bucket{handler="reverse_proxy",le="0.1"} 10
+caddy_http_request_duration_seconds_bucket{handler="reverse_proxy",le="0.5"} 30
+caddy_http_request_duration_seconds_bucket{handler="reverse_proxy",le="+Inf"} 40
+caddy_http_request_duration_seconds_bucket{handler="file_server",le="0.1"} 5
+caddy_http_request_duration_seconds_bucket{handler="file_server",le="0.5"} 5
+caddy_http_request_duration_seconds_bucket{handler="file_server",le="+Inf"} 5
+caddy_http_request_duration_seconds_sum{handler="reverse_proxy"} 9.5
+
+caddy_http_request_duration_seconds_count{handler="reverse_proxy"} 40
+`
+	pm, err := parsePrometheus(text)
+	if err != nil {
+		t.Fatalf("parsePrometheus() error = %v", err)
+	}
+	if got := pm.sum("caddy_http_request_duration_seconds_count", nil); got != 40 {
+		t.Errorf("sum(_count) = %v, want 40", got)
+	}
+	if got := pm.sum("caddy_http_request_duration_seconds_bucket", nil); got != 95 {
+		t.Errorf("sum(_bucket) = %v, want 95", got)
+	}
+
+	want := map[float64]float64{0.1: 15, 0.5: 35, math.Inf(1): 45}
+	if got := pm.buckets("caddy_http_request_duration_seconds", nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("buckets() = %v, want %v", got, want)
+	}
+	proxied := func(l map[string]string) bool { return l["handler"] == "reverse_proxy" }
+	want = map[float64]float64{0.1: 10, 0.5: 30, math.Inf(1): 40}
+	if got := pm.buckets("caddy_http_request_duration_seconds", proxied); !reflect.DeepEqual(got, want) {
+		t.Errorf("buckets(reverse_proxy) = %v, want %v", got, want)
+	}
+
+	if _, err := parsePrometheus("ok 1\nbroken{\n"); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("parsePrometheus() error = %v, want error on line 2", err)
+	}
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	inf := math.Inf(1)
+	tests := []struct {
+		name    string
+		q       float64
+		buckets map[float64]float64
+		want    float64
+	}{
+		{"empty", 0.5, map[float64]float64{}, 0},
+		{"no observations", 0.5, map[float64]float64{0.1: 0, inf: 0}, 0},
+		{"first bucket", 0.25, map[float64]float64{0.1: 10, 0.5: 30, 1: 40, inf: 40}, 0.1},
+		{"interpolated", 0.5, map[float64]float64{0.1: 10, 0.5: 30, 1: 40, inf: 40}, 0.3},
+		{"inside first bucket", 0.1, map[float64]float64{0.1: 10, 0.5: 30, 1: 40, inf: 40}, 0.04},
+		{"rank on bucket boundary", 0.5, map[float64]float64{0.1: 10, 0.5: 10, 1: 20, inf: 20}, 0.1},
+		{"zero quantile", 0, map[float64]float64{0.1: 0, 0.5: 10, inf: 10}, 0.1},
+		{"in +Inf bucket", 0.99, map[float64]float64{0.1: 10, 0.5: 15, inf: 20}, 0.5},
+		{"only +Inf bucket", 0.5, map[float64]float64{inf: 5}, 0},
+		{"no +Inf bucket", 1, map[float64]float64{0.1: 10, 0.5: 20}, 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := histogramQuantile(tt.q, tt.buckets); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("histogramQuantile(%v) = %v, want %v", tt.q, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBucketsRoundTrip(t *testing.T) {
+	buckets := map[float64]float64{0.005: 1, 0.1: 2, 2.5: 3, math.Inf(1): 4}
+	counters := map[string]float64{"requests": 9}
+	addBuckets(counters, "latency/", buckets)
+	if got := bucketsFrom(counters, "latency/"); !reflect.DeepEqual(got, buckets) {
+		t.Errorf("bucketsFrom(addBuckets()) = %v, want %v", got, buckets)
+	}
+}
+
+func TestCounterDeltas(t *testing.T) {
+	const id = "counter-deltas-test"
+	defer ForgetCounters(id)
+
+	if _, _, ok := counterDeltas(id, map[string]float64{"requests": 100}); ok {
+		t.Fatal("counterDeltas() reported deltas for a first reading")
+	}
+
+	// Move the stored reading back so the next one is a full window later.
+	back := func(d time.Duration) {
+		counterSamples.Lock()
+		s := counterSamples.m[id]
+		s.at = s.at.Add(-d)
+		counterSamples.m[id] = s
+		counterSamples.Unlock()
+	}
+
+	back(10 * time.Second)
+	deltas, seconds, ok := counterDeltas(id, map[string]float64{"requests": 150, "new": 7})
+	if !ok {
+		t.Fatal("counterDeltas() reported no deltas after a window")
+	}
+	if seconds < 10 || seconds > 11 {
+		t.Errorf("seconds = %v, want about 10", seconds)
+	}
+	if want := (map[string]float64{"requests": 50}); !reflect.DeepEqual(deltas, want) {
+		t.Errorf("deltas = %v, want %v (a counter with no previous reading has no delta)", deltas, want)
+	}
+
+	// A counter lower than before was reset by a restart; its value is
+	// its growth since then.
+	back(10 * time.Second)
+	deltas, _, ok = counterDeltas(id, map[string]float64{"requests": 20, "new": 9})
+	if !ok {
+		t.Fatal("counterDeltas() reported no deltas after a reset")
+	}
+	if want := (map[string]float64{"requests": 20, "new": 2}); !reflect.DeepEqual(deltas, want) {
+		t.Errorf("deltas after reset = %v, want %v", deltas, want)
+	}
+
+	// A reading inside minCounterWindow is diffed but does not replace the
+	// stored one.
+	back(10 * time.Second)
+	counterDeltas(id, map[string]float64{"requests": 30})
+	for _, tc := range []struct{ value, want float64 }{{40, 10}, {50, 20}} {
+		deltas, _, _ = counterDeltas(id, map[string]float64{"requests": tc.value})
+		if deltas["requests"] != tc.want {
+			t.Errorf("deltas[requests] at %v = %v, want %v against the reading at 30", tc.value, deltas["requests"], tc.want)
+		}
+	}
+
+	ForgetCounters(id)
+	if _, _, ok := counterDeltas(id, map[string]float64{"requests": 1}); ok {
+		t.Error("counterDeltas() reported deltas after ForgetCounters")
+	}
+}
@@ -0,0 +1,73 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// stageSuffix marks the temp files Traefik config writes are staged in,
+// beside their target so the final rename is atomic. Traefik's file
+// provider only loads .yml, .yaml and .toml files, so it ignores them.
+// This does not hold for proxies that include a directory with a bare *
+// glob, such as NGINX, which stage elsewhere.
+const stageSuffix = ".proxera-new"
+
+// runCommand runs cmd in a new session on client, feeding it stdin if set,
+// and returns combined stdout+stderr output.
+func runCommand(client *ssh.Client, cmd string, stdin io.Reader) (string, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+	if stdin != nil {
+		session.Stdin = stdin
+	}
+	return runSession(session, cmd)
+}
+
+// wrapCommand runs script through sh, inside container if set and under
+// sudo if asked to.
+func wrapCommand(script string, sudo bool, container string) string {
+	cmd := "sh -c " + shellQuote(script)
+	if container != "" {
+		cmd = "docker exec -i " + shellQuote(container) + " " + cmd
+	}
+	if sudo {
+		cmd = "sudo " + cmd
+	}
+	return cmd
+}
+
+// stageScript copies stdin into a fresh temp file made from the mktemp
+// template tmpl, carrying over target's owner and mode (0644 for a new
+// file), and prints the temp file's path. target's directory is created if
+// missing. Nothing is left behind if any step fails.
+func stageScript(target, tmpl string) string {
+	return fmt.Sprintf(`mkdir -p %[2]s && tmp=$(mktemp %[3]s) || exit 1; `+
+		`{ cat >"$tmp" && if [ -e %[1]s ]; then `+
+		`chown "$(stat -c %%u:%%g %[1]s)" "$tmp" && chmod "$(stat -c %%a %[1]s)" "$tmp"; `+
+		`else chmod 0644 "$tmp"; fi && echo "$tmp"; } || { rm -f "$tmp"; exit 1; }`,
+		shellQuote(target), shellQuote(path.Dir(target)), shellQuote(tmpl))
+}
+
+// commandError describes a failed remote command by its output, or by the
+// error itself when it printed nothing.
+func commandError(out string, err error) string {
+	if out = strings.TrimSpace(out); out != "" {
+		return out
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return "unexpected output"
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,289 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/anveesa/proxera/models"
+)
+
+// Markers delimiting the Proxera-managed region inside a text config
+// (NGINX, HAProxy). Everything outside them belongs to the operator.
+const (
+	managedBegin = "# BEGIN PROXERA ROUTES (generated by Proxera, do not edit by hand)"
+	managedEnd   = "# END PROXERA ROUTES"
+)
+
+// Middleware names with a native equivalent in every renderer. Any other
+// name is passed through on Traefik and skipped (with a comment) elsewhere.
+const (
+	mwGzip          = "gzip"
+	mwCORS          = "cors"
+	mwRedirectHTTPS = "redirect-https"
+)
+
+// RenderRoutes turns a server's routes into configuration for proxyType.
+// Disabled routes are skipped. current is the proxy's existing config and
+// is used to preserve whatever Proxera does not manage; it may be empty.
+func RenderRoutes(proxyType string, routes []models.Route, current string) (string, error) {
+	routes = append([]models.Route(nil), routes...)
+	for i := range routes {
+		NormalizeRoute(&routes[i])
+	}
+	if err := ValidateRoutes(routes); err != nil {
+		return "", err
+	}
+	active := activeRoutes(routes)
+	switch proxyType {
+	case "nginx":
+		return renderNGINX(active, current)
+	case "caddy":
+		return renderCaddy(active, current)
+	case "haproxy":
+		return renderHAProxy(active, current)
+	case "traefik":
+		return renderTraefik(active)
+	default:
+		return "", &ErrNotSupported{Op: "RenderRoutes"}
+	}
+}
+
+// routeMethodsAllowed are the methods a route may match on.
+var routeMethodsAllowed = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true,
+	"DELETE": true, "OPTIONS": true, "CONNECT": true, "TRACE": true,
+}
+
+// hostnameRe matches a host name, optionally with a leading "*." wildcard
+// label. IPv4 addresses match too.
+var hostnameRe = regexp.MustCompile(`^(\*\.)?([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+// upstreamHostRe matches an upstream host name. It is looser than
+// hostnameRe, as container and service names often have underscores.
+var upstreamHostRe = regexp.MustCompile(`^[a-zA-Z0-9_]([a-zA-Z0-9_.-]*[a-zA-Z0-9_])?$`)
+
+// NormalizeRoute puts r's match fields in the form they are compared and
+// rendered in: host names are case-insensitive, so MatchHost is lowercased.
+func NormalizeRoute(r *models.Route) {
+	r.MatchHost = strings.ToLower(r.MatchHost)
+}
+
+// ValidateRoute checks the route fields that are written into proxy
+// configs as they are: every renderer puts the host, path and methods in
+// directives, rules or file paths unquoted, so anything outside their
+// grammar could change the config around them.
+func ValidateRoute(r models.Route) error {
+	if r.MatchHost != "" && (len(r.MatchHost) > 253 || !hostnameRe.MatchString(r.MatchHost)) {
+		return fmt.Errorf("matchHost %q is not a host name or *.wildcard", r.MatchHost)
+	}
+	if r.MatchPath != "" {
+		if !strings.HasPrefix(r.MatchPath, "/") {
+			return fmt.Errorf("matchPath %q must start with /", r.MatchPath)
+		}
+		if strings.IndexFunc(r.MatchPath, func(c rune) bool {
+			return unicode.IsSpace(c) || unicode.IsControl(c) || strings.ContainsRune(";{}#`\"'\\", c)
+		}) >= 0 {
+			return fmt.Errorf("matchPath %q may not contain whitespace or any of ;{}#`\"'\\", r.MatchPath)
+		}
+	}
+	for _, m := range routeMethods(r) {
+		if !routeMethodsAllowed[m] {
+			return fmt.Errorf("matchMethod %q is not an HTTP method", m)
+		}
+	}
+	if _, err := parseUpstreams(r.TargetUpstream); err != nil {
+		return fmt.Errorf("targetUpstream: %w", err)
+	}
+	return nil
+}
+
+// ValidateRoutes runs ValidateRoute on a server's enabled routes, then
+// DuplicateRoutes on them.
+func ValidateRoutes(routes []models.Route) error {
+	for _, r := range routes {
+		if !r.Enabled {
+			continue
+		}
+		if err := ValidateRoute(r); err != nil {
+			return fmt.Errorf("route %q: %w", r.Name, err)
+		}
+	}
+	return DuplicateRoutes(routes)
+}
+
+// DuplicateRoutes rejects two enabled routes of a server matching the same
+// host and path on the same listener, which NGINX refuses as a duplicate
+// location and the other proxies would resolve arbitrarily. An HTTP route
+// redirecting to an HTTPS one with the same match is fine. Routes are
+// expected to have been through NormalizeRoute.
+func DuplicateRoutes(routes []models.Route) error {
+	seen := map[string]string{}
+	for _, r := range routes {
+		if !r.Enabled {
+			continue
+		}
+		key := fmt.Sprintf("%s|%s|%t", r.MatchHost, routePath(r), r.SSLEnabled)
+		if other, ok := seen[key]; ok {
+			return fmt.Errorf("routes %q and %q both match host %q and path %q", other, r.Name, r.MatchHost, routePath(r))
+		}
+		seen[key] = r.Name
+	}
+	return nil
+}
+
+// upstreamTarget is one backend address parsed from Route.TargetUpstream.
+type upstreamTarget struct {
+	scheme string // http or https
+	addr   string // host:port
+}
+
+// parseUpstreams splits a TargetUpstream ("http://a:3000, http://b:3000")
+// into individual targets, defaulting the scheme to http.
+func parseUpstreams(target string) ([]upstreamTarget, error) {
+	var out []upstreamTarget
+	for _, raw := range strings.FieldsFunc(target, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if !strings.Contains(raw, "://") {
+			raw = "http://" + raw
+		}
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid upstream %q", raw)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("unsupported upstream scheme %q in %q", u.Scheme, raw)
+		}
+		if h := u.Hostname(); !upstreamHostRe.MatchString(h) && net.ParseIP(h) == nil {
+			return nil, fmt.Errorf("invalid upstream host %q", h)
+		}
+		addr := u.Host
+		if u.Port() == "" {
+			if u.Scheme == "https" {
+				addr += ":443"
+			} else {
+				addr += ":80"
+			}
+		}
+		out = append(out, upstreamTarget{scheme: u.Scheme, addr: addr})
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("empty upstream")
+	}
+	return out, nil
+}
+
+// activeRoutes returns the enabled routes ordered by descending priority,
+// then name, so output is stable between syncs.
+func activeRoutes(routes []models.Route) []models.Route {
+	out := make([]models.Route, 0, len(routes))
+	for _, r := range routes {
+		if r.Enabled {
+			out = append(out, r)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Priority != out[j].Priority {
+			return out[i].Priority > out[j].Priority
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func routeMiddlewares(r models.Route) []string {
+	if r.Middlewares != nil {
+		return r.Middlewares
+	}
+	var mws []string
+	if r.MiddlewaresJSON != "" {
+		json.Unmarshal([]byte(r.MiddlewaresJSON), &mws) //nolint:errcheck
+	}
+	return mws
+}
+
+func hasMiddleware(r models.Route, name string) bool {
+	for _, m := range routeMiddlewares(r) {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+func routeMethods(r models.Route) []string {
+	var out []string
+	for _, m := range strings.FieldsFunc(r.MatchMethod, func(c rune) bool { return c == ',' || c == ' ' || c == '|' }) {
+		out = append(out, strings.ToUpper(m))
+	}
+	return out
+}
+
+func routePath(r models.Route) string {
+	if r.MatchPath == "" {
+		return "/"
+	}
+	return r.MatchPath
+}
+
+// routeComment returns the route's name for a config comment line, with
+// control characters (newlines in particular) removed.
+func routeComment(r models.Route) string {
+	return strings.Map(func(c rune) rune {
+		if unicode.IsControl(c) {
+			return -1
+		}
+		return c
+	}, r.Name)
+}
+
+var identUnsafe = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// routeIdent returns a config-safe identifier that is unique per route.
+func routeIdent(r models.Route) string {
+	slug := strings.Trim(identUnsafe.ReplaceAllString(strings.ToLower(r.Name), "_"), "_")
+	id := r.ID
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	if slug == "" {
+		return "route_" + id
+	}
+	return slug + "_" + id
+}
+
+// managedRegion wraps block in the managed-region markers, indenting every
+// line with prefix.
+func managedRegion(block, prefix string) string {
+	return indent(managedBegin+"\n"+block+managedEnd+"\n", prefix)
+}
+
+// spliceManaged replaces the managed region of current with region, or
+// calls insert to place it when current has none yet.
+func spliceManaged(current, region string, insert func(current, region string) string) string {
+	if i := strings.Index(current, managedBegin); i >= 0 {
+		if j := strings.Index(current[i:], managedEnd); j >= 0 {
+			start := strings.LastIndex(current[:i], "\n") + 1
+			end := i + j + len(managedEnd)
+			if end < len(current) && current[end] == '\n' {
+				end++
+			}
+			return current[:start] + region + current[end:]
+		}
+	}
+	return insert(current, region)
+}
+
+func indent(s, prefix string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, l := range lines {
+		if l != "" {
+			lines[i] = prefix + l
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,162 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/anveesa/proxera/models"
+)
+
+// Caddy servers owned by Proxera inside apps.http.servers. Any other
+// server in the current config is left untouched.
+const (
+	caddyHTTPServer  = "proxera_http"
+	caddyHTTPSServer = "proxera_https"
+)
+
+// caddySelectionPolicy maps a load-balancing method onto reverse_proxy's
+// selection_policy; the names happen to match Caddy's built-in policies.
+var caddySelectionPolicy = map[models.LoadBalancingMethod]string{
+	models.LBRoundRobin: "round_robin",
+	models.LBLeastConn:  "least_conn",
+	models.LBIPHash:     "ip_hash",
+	models.LBRandom:     "random",
+}
+
+// renderCaddy builds the Proxera-owned HTTP servers as Caddy JSON and merges
+// them into current (the JSON returned by the admin API), preserving the
+// admin endpoint, other apps and other servers.
+func renderCaddy(routes []models.Route, current string) (string, error) {
+	var httpRoutes, httpsRoutes []map[string]interface{}
+	for _, r := range routes {
+		route, err := caddyRoute(r)
+		if err != nil {
+			return "", fmt.Errorf("route %q: %w", r.Name, err)
+		}
+		if r.SSLEnabled {
+			httpsRoutes = append(httpsRoutes, route)
+		} else {
+			httpRoutes = append(httpRoutes, route)
+		}
+	}
+
+	cfg := map[string]interface{}{}
+	if strings.TrimSpace(current) != "" && strings.TrimSpace(current) != "null" {
+		if err := json.Unmarshal([]byte(current), &cfg); err != nil {
+			return "", fmt.Errorf("parse current caddy config: %w", err)
+		}
+	}
+	apps := childMap(cfg, "apps")
+	httpApp := childMap(apps, "http")
+	servers := childMap(httpApp, "servers")
+
+	delete(servers, caddyHTTPServer)
+	delete(servers, caddyHTTPSServer)
+	if len(httpRoutes) > 0 {
+		servers[caddyHTTPServer] = map[string]interface{}{
+			"listen":          []string{":80"},
+			"routes":          httpRoutes,
+			"automatic_https": map[string]interface{}{"disable": true},
+		}
+	}
+	if len(httpsRoutes) > 0 {
+		servers[caddyHTTPSServer] = map[string]interface{}{
+			"listen": []string{":443"},
+			"routes": httpsRoutes,
+		}
+	}
+
+	out, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func caddyRoute(r models.Route) (map[string]interface{}, error) {
+	targets, err := parseUpstreams(r.TargetUpstream)
+	if err != nil {
+		return nil, err
+	}
+
+	match := map[string]interface{}{}
+	if r.MatchHost != "" {
+		match["host"] = []string{r.MatchHost}
+	}
+	if p := routePath(r); p != "/" {
+		if !strings.HasSuffix(p, "*") {
+			p += "*"
+		}
+		match["path"] = []string{p}
+	}
+	if methods := routeMethods(r); len(methods) > 0 {
+		match["method"] = methods
+	}
+
+	var handle []map[string]interface{}
+	if !r.SSLEnabled && hasMiddleware(r, mwRedirectHTTPS) {
+		handle = append(handle, map[string]interface{}{
+			"handler":     "static_response",
+			"status_code": 301,
+			"headers": map[string][]string{
+				"Location": {"https://{http.request.host}{http.request.uri}"},
+			},
+		})
+	} else {
+		for _, m := range routeMiddlewares(r) {
+			switch m {
+			case mwGzip:
+				handle = append(handle, map[string]interface{}{
+					"handler":   "encode",
+					"encodings": map[string]interface{}{"gzip": map[string]interface{}{}},
+				})
+			case mwCORS:
+				handle = append(handle, map[string]interface{}{
+					"handler": "headers",
+					"response": map[string]interface{}{
+						"set": map[string][]string{"Access-Control-Allow-Origin": {"*"}},
+					},
+				})
+			}
+		}
+
+		upstreams := make([]map[string]string, 0, len(targets))
+		for _, t := range targets {
+			upstreams = append(upstreams, map[string]string{"dial": t.addr})
+		}
+		proxy := map[string]interface{}{
+			"handler":   "reverse_proxy",
+			"upstreams": upstreams,
+		}
+		if policy, ok := caddySelectionPolicy[r.LoadBalancingMethod]; ok {
+			proxy["load_balancing"] = map[string]interface{}{
+				"selection_policy": map[string]string{"policy": policy},
+			}
+		}
+		if targets[0].scheme == "https" {
+			proxy["transport"] = map[string]interface{}{"protocol": "http", "tls": map[string]interface{}{}}
+		}
+		handle = append(handle, proxy)
+	}
+
+	route := map[string]interface{}{
+		"@id":      "proxera_" + routeIdent(r),
+		"handle":   handle,
+		"terminal": true,
+	}
+	if len(match) > 0 {
+		route["match"] = []map[string]interface{}{match}
+	}
+	return route, nil
+}
+
+// childMap returns m[key] as a map, creating it if missing or mistyped.
+func childMap(m map[string]interface{}, key string) map[string]interface{} {
+	if child, ok := m[key].(map[string]interface{}); ok {
+		return child
+	}
+	child := map[string]interface{}{}
+	m[key] = child
+	return child
+}
@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/anveesa/proxera/models"
+)
+
+// haproxyBalance maps a load-balancing method onto the backend balance
+// algorithm.
+var haproxyBalance = map[models.LoadBalancingMethod]string{
+	models.LBRoundRobin: "roundrobin",
+	models.LBLeastConn:  "leastconn",
+	models.LBIPHash:     "source",
+	models.LBRandom:     "random",
+}
+
+const haproxyDefaults = `global
+    log stdout format raw local0
+
+defaults
+    mode http
+    log global
+    option httplog
+    timeout connect 5s
+    timeout client 30s
+    timeout server 30s
+`
+
+// renderHAProxy emits one frontend per listener (http/https) and one
+// backend per route. The sections are appended to current inside the
+// managed region, or prefixed with minimal global/defaults sections when
+// there is no current config.
+func renderHAProxy(routes []models.Route, current string) (string, error) {
+	var httpRules, httpsRules, backends strings.Builder
+
+	for _, r := range routes {
+		targets, err := parseUpstreams(r.TargetUpstream)
+		if err != nil {
+			return "", fmt.Errorf("route %q: %w", r.Name, err)
+		}
+		ident := routeIdent(r)
+
+		var acls []string
+		rules := &httpRules
+		if r.SSLEnabled {
+			rules = &httpsRules
+		}
+		fmt.Fprintf(rules, "    # %s\n", routeComment(r))
+		if r.MatchHost != "" {
+			fmt.Fprintf(rules, "    acl %s_host hdr(host),field(1,:) -i %s\n", ident, r.MatchHost)
+			acls = append(acls, ident+"_host")
+		}
+		if p := routePath(r); p != "/" {
+			fmt.Fprintf(rules, "    acl %s_path path_beg %s\n", ident, p)
+			acls = append(acls, ident+"_path")
+		}
+		if methods := routeMethods(r); len(methods) > 0 {
+			fmt.Fprintf(rules, "    acl %s_method method %s\n", ident, strings.Join(methods, " "))
+			acls = append(acls, ident+"_method")
+		}
+		cond := ""
+		if len(acls) > 0 {
+			cond = " if " + strings.Join(acls, " ")
+		}
+		if !r.SSLEnabled && hasMiddleware(r, mwRedirectHTTPS) {
+			fmt.Fprintf(rules, "    http-request redirect scheme https code 301%s\n", cond)
+			continue
+		}
+		fmt.Fprintf(rules, "    use_backend be_%s%s\n", ident, cond)
+
+		fmt.Fprintf(&backends, "backend be_%s\n", ident)
+		if algo, ok := haproxyBalance[r.LoadBalancingMethod]; ok {
+			fmt.Fprintf(&backends, "    balance %s\n", algo)
+		}
+		for _, m := range routeMiddlewares(r) {
+			switch m {
+			case mwGzip:
+				backends.WriteString("    compression algo gzip\n")
+			case mwCORS:
+				backends.WriteString("    http-response set-header Access-Control-Allow-Origin \"*\"\n")
+			case mwRedirectHTTPS:
+			default:
+				fmt.Fprintf(&backends, "    # middleware %q has no HAProxy equivalent; skipped\n", m)
+			}
+		}
+		for i, t := range targets {
+			line := fmt.Sprintf("    server s%d %s check", i+1, t.addr)
+			if t.scheme == "https" {
+				line += " ssl verify none"
+			}
+			backends.WriteString(line + "\n")
+		}
+		backends.WriteString("\n")
+	}
+
+	var b strings.Builder
+	if httpRules.Len() > 0 {
+		b.WriteString("frontend proxera_http\n    bind *:80\n")
+		b.WriteString(httpRules.String())
+		b.WriteString("\n")
+	}
+	if httpsRules.Len() > 0 {
+		b.WriteString("frontend proxera_https\n    bind *:443 ssl crt /etc/haproxy/certs/\n")
+		b.WriteString(httpsRules.String())
+		b.WriteString("\n")
+	}
+	b.WriteString(backends.String())
+
+	region := managedRegion(strings.TrimRight(b.String(), "\n")+"\n", "")
+	if strings.TrimSpace(current) == "" {
+		return haproxyDefaults + "\n" + region, nil
+	}
+	return spliceManaged(current, region, func(current, region string) string {
+		return strings.TrimRight(current, "\n") + "\n\n" + region
+	}), nil
+}
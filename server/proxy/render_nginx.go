@@ -0,0 +1,164 @@
+package proxy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/anveesa/proxera/models"
+)
+
+// nginxLBDirective maps a load-balancing method onto the upstream directive.
+// Round robin is NGINX's default and needs none.
+var nginxLBDirective = map[models.LoadBalancingMethod]string{
+	models.LBLeastConn: "least_conn;",
+	models.LBIPHash:    "ip_hash;",
+	models.LBRandom:    "random;",
+}
+
+// renderNGINX emits upstream and server blocks for routes. They are placed
+// inside the existing http block of current, or into a minimal standalone
+// nginx.conf when there is no current config.
+func renderNGINX(routes []models.Route, current string) (string, error) {
+	var b strings.Builder
+
+	type vhost struct {
+		host   string
+		ssl    bool
+		routes []models.Route
+	}
+	var vhosts []*vhost
+	byKey := make(map[string]*vhost)
+
+	for _, r := range routes {
+		targets, err := parseUpstreams(r.TargetUpstream)
+		if err != nil {
+			return "", fmt.Errorf("route %q: %w", r.Name, err)
+		}
+
+		fmt.Fprintf(&b, "upstream %s {\n", routeIdent(r))
+		if d, ok := nginxLBDirective[r.LoadBalancingMethod]; ok {
+			fmt.Fprintf(&b, "    %s\n", d)
+		}
+		for _, t := range targets {
+			fmt.Fprintf(&b, "    server %s;\n", t.addr)
+		}
+		b.WriteString("}\n\n")
+
+		key := fmt.Sprintf("%s|%t", r.MatchHost, r.SSLEnabled)
+		vh, ok := byKey[key]
+		if !ok {
+			vh = &vhost{host: r.MatchHost, ssl: r.SSLEnabled}
+			byKey[key] = vh
+			vhosts = append(vhosts, vh)
+		}
+		vh.routes = append(vh.routes, r)
+	}
+
+	for _, vh := range vhosts {
+		serverName := vh.host
+		if serverName == "" {
+			serverName = "_"
+		}
+		b.WriteString("server {\n")
+		if vh.ssl {
+			b.WriteString("    listen 443 ssl;\n")
+			fmt.Fprintf(&b, "    server_name %s;\n", serverName)
+			fmt.Fprintf(&b, "    ssl_certificate /etc/nginx/ssl/%s/fullchain.pem;\n", serverName)
+			fmt.Fprintf(&b, "    ssl_certificate_key /etc/nginx/ssl/%s/privkey.pem;\n", serverName)
+		} else {
+			b.WriteString("    listen 80;\n")
+			fmt.Fprintf(&b, "    server_name %s;\n", serverName)
+		}
+		for _, r := range vh.routes {
+			b.WriteString("\n")
+			writeNGINXLocation(&b, r, vh.ssl)
+		}
+		b.WriteString("}\n\n")
+	}
+
+	region := managedRegion(strings.TrimRight(b.String(), "\n")+"\n", "    ")
+	if strings.TrimSpace(current) == "" {
+		return "events {}\n\nhttp {\n" + region + "}\n", nil
+	}
+	return spliceManaged(current, region, insertIntoHTTPBlock), nil
+}
+
+func writeNGINXLocation(b *strings.Builder, r models.Route, ssl bool) {
+	targets, _ := parseUpstreams(r.TargetUpstream)
+	fmt.Fprintf(b, "    # %s\n", routeComment(r))
+	fmt.Fprintf(b, "    location %s {\n", routePath(r))
+
+	if !ssl && hasMiddleware(r, mwRedirectHTTPS) {
+		b.WriteString("        return 301 https://$host$request_uri;\n")
+		b.WriteString("    }\n")
+		return
+	}
+	if methods := routeMethods(r); len(methods) > 0 {
+		fmt.Fprintf(b, "        limit_except %s {\n            deny all;\n        }\n", strings.Join(methods, " "))
+	}
+	for _, m := range routeMiddlewares(r) {
+		switch m {
+		case mwGzip:
+			b.WriteString("        gzip on;\n")
+		case mwCORS:
+			b.WriteString("        add_header Access-Control-Allow-Origin * always;\n")
+		case mwRedirectHTTPS:
+		default:
+			fmt.Fprintf(b, "        # middleware %q has no NGINX equivalent; skipped\n", m)
+		}
+	}
+	fmt.Fprintf(b, "        proxy_pass %s://%s;\n", targets[0].scheme, routeIdent(r))
+	b.WriteString("        proxy_set_header Host $host;\n")
+	b.WriteString("        proxy_set_header X-Real-IP $remote_addr;\n")
+	b.WriteString("        proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;\n")
+	b.WriteString("        proxy_set_header X-Forwarded-Proto $scheme;\n")
+	b.WriteString("    }\n")
+}
+
+var nginxHTTPBlock = regexp.MustCompile(`(?m)^\s*http\s*\{`)
+
+// insertIntoHTTPBlock places region just before the closing brace of the
+// top-level http block, or appends it if none is found.
+func insertIntoHTTPBlock(current, region string) string {
+	loc := nginxHTTPBlock.FindStringIndex(current)
+	if loc == nil {
+		return strings.TrimRight(current, "\n") + "\n\n" + region
+	}
+	if end := matchingBrace(current, loc[1]-1); end >= 0 {
+		return current[:end] + "\n" + region + current[end:]
+	}
+	return strings.TrimRight(current, "\n") + "\n\n" + region
+}
+
+// matchingBrace returns the index of the '}' closing the '{' at open,
+// ignoring braces inside comments and quoted strings, or -1.
+func matchingBrace(s string, open int) int {
+	depth := 0
+	var quote byte
+	for i := open; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '#':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,131 @@
+package proxy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/anveesa/proxera/models"
+)
+
+func TestValidateRoute(t *testing.T) {
+	tests := []struct {
+		name    string
+		route   models.Route
+		wantErr string
+	}{
+		{"minimal", models.Route{TargetUpstream: "app:3000"}, ""},
+		{"full", models.Route{
+			MatchHost: "api.example.com", MatchPath: "/v1/users", MatchMethod: "get, POST|delete",
+			TargetUpstream: "http://my_app:3000, https://10.0.0.2, http://[::1]:8080",
+		}, ""},
+		{"wildcard host", models.Route{MatchHost: "*.example.com", TargetUpstream: "app"}, ""},
+		{"ipv4 host", models.Route{MatchHost: "10.0.0.1", TargetUpstream: "app"}, ""},
+
+		{"host with path traversal", models.Route{MatchHost: "../../etc", TargetUpstream: "app"}, "matchHost"},
+		{"host with directive", models.Route{MatchHost: "a.com; include /etc/passwd", TargetUpstream: "app"}, "matchHost"},
+		{"host with backtick", models.Route{MatchHost: "a.com`) || Host(`b.com", TargetUpstream: "app"}, "matchHost"},
+		{"wildcard not leading", models.Route{MatchHost: "api.*.com", TargetUpstream: "app"}, "matchHost"},
+		{"host label too long", models.Route{MatchHost: strings.Repeat("a", 64) + ".com", TargetUpstream: "app"}, "matchHost"},
+		{"host with underscore", models.Route{MatchHost: "my_host.com", TargetUpstream: "app"}, "matchHost"},
+
+		{"relative path", models.Route{MatchPath: "api", TargetUpstream: "app"}, "must start with /"},
+		{"path with space", models.Route{MatchPath: "/a b", TargetUpstream: "app"}, "matchPath"},
+		{"path with semicolon", models.Route{MatchPath: "/a;return 200", TargetUpstream: "app"}, "matchPath"},
+		{"path with brace", models.Route{MatchPath: "/a{", TargetUpstream: "app"}, "matchPath"},
+		{"path with comment", models.Route{MatchPath: "/a#", TargetUpstream: "app"}, "matchPath"},
+		{"path with backtick", models.Route{MatchPath: "/a`)", TargetUpstream: "app"}, "matchPath"},
+		{"path with quote", models.Route{MatchPath: `/a"`, TargetUpstream: "app"}, "matchPath"},
+		{"path with newline", models.Route{MatchPath: "/a\nlisten 8080", TargetUpstream: "app"}, "matchPath"},
+
+		{"unknown method", models.Route{MatchMethod: "GET,FETCH", TargetUpstream: "app"}, `"FETCH"`},
+		{"method with directive", models.Route{MatchMethod: "GET;", TargetUpstream: "app"}, "matchMethod"},
+
+		{"empty upstream", models.Route{}, "targetUpstream"},
+		{"upstream scheme", models.Route{TargetUpstream: "ftp://app"}, "targetUpstream"},
+		{"upstream host with directive", models.Route{TargetUpstream: "http://a;b:80"}, "targetUpstream"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRoute(tt.route)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateRoute() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ValidateRoute() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDuplicateRoutes(t *testing.T) {
+	route := func(name, host, path string, ssl, enabled bool) models.Route {
+		return models.Route{Name: name, MatchHost: host, MatchPath: path, SSLEnabled: ssl, Enabled: enabled, TargetUpstream: "app"}
+	}
+	tests := []struct {
+		name    string
+		routes  []models.Route
+		wantErr bool
+	}{
+		{"distinct paths", []models.Route{route("a", "x.com", "/a", false, true), route("b", "x.com", "/b", false, true)}, false},
+		{"distinct hosts", []models.Route{route("a", "x.com", "/", false, true), route("b", "y.com", "/", false, true)}, false},
+		{"same match", []models.Route{route("a", "x.com", "/a", false, true), route("b", "x.com", "/a", false, true)}, true},
+		{"empty path is /", []models.Route{route("a", "x.com", "", false, true), route("b", "x.com", "/", false, true)}, true},
+		{"other listener", []models.Route{route("a", "x.com", "/", false, true), route("b", "x.com", "/", true, true)}, false},
+		{"disabled duplicate", []models.Route{route("a", "x.com", "/", false, true), route("b", "x.com", "/", false, false)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DuplicateRoutes(tt.routes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DuplicateRoutes() = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRenderRoutesNormalizesHost(t *testing.T) {
+	routes := []models.Route{
+		{ID: "1", Name: "a", Enabled: true, MatchHost: "Example.com", MatchPath: "/a", TargetUpstream: "app"},
+		{ID: "2", Name: "b", Enabled: true, MatchHost: "example.com", MatchPath: "/b", TargetUpstream: "app"},
+	}
+	out, err := RenderRoutes("nginx", routes, "")
+	if err != nil {
+		t.Fatalf("RenderRoutes() error = %v", err)
+	}
+	if n := strings.Count(out, "server_name example.com;"); n != 1 {
+		t.Errorf("rendered %d server blocks for example.com, want 1:\n%s", n, out)
+	}
+	if routes[0].MatchHost != "Example.com" {
+		t.Errorf("RenderRoutes modified its input: MatchHost = %q", routes[0].MatchHost)
+	}
+
+	routes[1].MatchPath = "/a"
+	if _, err := RenderRoutes("nginx", routes, ""); err == nil {
+		t.Error("RenderRoutes() accepted two routes matching example.com/a in different case")
+	}
+}
+
+func TestRenderRoutesRejectsInvalidRoute(t *testing.T) {
+	routes := []models.Route{{ID: "1", Name: "a", Enabled: true, MatchHost: "x.com;", TargetUpstream: "app"}}
+	for _, proxyType := range []string{"nginx", "haproxy", "traefik", "caddy"} {
+		if _, err := RenderRoutes(proxyType, routes, ""); err == nil {
+			t.Errorf("RenderRoutes(%q) accepted an invalid host", proxyType)
+		}
+	}
+}
+
+func TestRouteCommentStripsControlCharacters(t *testing.T) {
+	routes := []models.Route{{ID: "1", Name: "api\n}\nserver {", Enabled: true, TargetUpstream: "app"}}
+	for _, proxyType := range []string{"nginx", "haproxy"} {
+		out, err := RenderRoutes(proxyType, routes, "")
+		if err != nil {
+			t.Fatalf("RenderRoutes(%q) error = %v", proxyType, err)
+		}
+		if !strings.Contains(out, "# api}server {\n") {
+			t.Errorf("RenderRoutes(%q) did not keep the name on its comment line:\n%s", proxyType, out)
+		}
+	}
+}
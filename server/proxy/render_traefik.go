@@ -0,0 +1,131 @@
+package proxy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/anveesa/proxera/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Traefik dynamic configuration (file provider) as emitted by Proxera.
+type traefikDynamic struct {
+	HTTP traefikHTTP `yaml:"http"`
+}
+
+type traefikHTTP struct {
+	Routers     map[string]traefikRouter  `yaml:"routers,omitempty"`
+	Services    map[string]traefikService `yaml:"services,omitempty"`
+	Middlewares map[string]map[string]any `yaml:"middlewares,omitempty"`
+}
+
+type traefikRouter struct {
+	Rule        string         `yaml:"rule"`
+	EntryPoints []string       `yaml:"entryPoints"`
+	Service     string         `yaml:"service"`
+	Priority    int            `yaml:"priority,omitempty"`
+	Middlewares []string       `yaml:"middlewares,omitempty"`
+	TLS         map[string]any `yaml:"tls,omitempty"`
+}
+
+type traefikService struct {
+	LoadBalancer traefikLoadBalancer `yaml:"loadBalancer"`
+}
+
+type traefikLoadBalancer struct {
+	Servers  []traefikServer `yaml:"servers"`
+	Strategy string          `yaml:"strategy,omitempty"`
+	Sticky   map[string]any  `yaml:"sticky,omitempty"`
+}
+
+type traefikServer struct {
+	URL string `yaml:"url"`
+}
+
+// renderTraefik emits a complete dynamic configuration file. Traefik has
+// no least-connections or hashing balancer: least_conn and random map onto
+// power-of-two-choices, ip_hash onto cookie stickiness.
+func renderTraefik(routes []models.Route) (string, error) {
+	dyn := traefikDynamic{HTTP: traefikHTTP{
+		Routers:     map[string]traefikRouter{},
+		Services:    map[string]traefikService{},
+		Middlewares: map[string]map[string]any{},
+	}}
+
+	for _, r := range routes {
+		targets, err := parseUpstreams(r.TargetUpstream)
+		if err != nil {
+			return "", fmt.Errorf("route %q: %w", r.Name, err)
+		}
+		ident := routeIdent(r)
+
+		var rule []string
+		if r.MatchHost != "" {
+			rule = append(rule, fmt.Sprintf("Host(`%s`)", r.MatchHost))
+		}
+		if p := routePath(r); p != "/" {
+			rule = append(rule, fmt.Sprintf("PathPrefix(`%s`)", p))
+		}
+		if methods := routeMethods(r); len(methods) > 0 {
+			var alts []string
+			for _, m := range methods {
+				alts = append(alts, fmt.Sprintf("Method(`%s`)", m))
+			}
+			rule = append(rule, "("+strings.Join(alts, " || ")+")")
+		}
+		if len(rule) == 0 {
+			rule = append(rule, "PathPrefix(`/`)")
+		}
+
+		router := traefikRouter{
+			Rule:        strings.Join(rule, " && "),
+			EntryPoints: []string{"web"},
+			Service:     ident,
+			Priority:    r.Priority,
+		}
+		if r.SSLEnabled {
+			router.EntryPoints = []string{"websecure"}
+			router.TLS = map[string]any{}
+		}
+		for _, m := range routeMiddlewares(r) {
+			switch m {
+			case mwGzip:
+				dyn.HTTP.Middlewares["proxera-gzip"] = map[string]any{"compress": map[string]any{}}
+				router.Middlewares = append(router.Middlewares, "proxera-gzip")
+			case mwCORS:
+				dyn.HTTP.Middlewares["proxera-cors"] = map[string]any{"headers": map[string]any{
+					"accessControlAllowOriginList": []string{"*"},
+				}}
+				router.Middlewares = append(router.Middlewares, "proxera-cors")
+			case mwRedirectHTTPS:
+				dyn.HTTP.Middlewares["proxera-redirect-https"] = map[string]any{"redirectScheme": map[string]any{
+					"scheme":    "https",
+					"permanent": true,
+				}}
+				router.Middlewares = append(router.Middlewares, "proxera-redirect-https")
+			default:
+				// Defined elsewhere in Traefik (e.g. "auth@file"); reference as-is.
+				router.Middlewares = append(router.Middlewares, m)
+			}
+		}
+		dyn.HTTP.Routers[ident] = router
+
+		lb := traefikLoadBalancer{}
+		for _, t := range targets {
+			lb.Servers = append(lb.Servers, traefikServer{URL: t.scheme + "://" + t.addr})
+		}
+		switch r.LoadBalancingMethod {
+		case models.LBLeastConn, models.LBRandom:
+			lb.Strategy = "p2c"
+		case models.LBIPHash:
+			lb.Sticky = map[string]any{"cookie": map[string]any{"name": "proxera_" + ident}}
+		}
+		dyn.HTTP.Services[ident] = traefikService{LoadBalancer: lb}
+	}
+
+	out, err := yaml.Marshal(dyn)
+	if err != nil {
+		return "", err
+	}
+	return managedBegin + "\n" + string(out), nil
+}
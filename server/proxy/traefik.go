@@ -2,7 +2,6 @@ package proxy
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,29 +11,52 @@ import (
 	"github.com/anveesa/proxera/models"
 )
 
-// TraefikAdapter connects to Traefik via its REST API.
+// TraefikAdapter connects to Traefik via its REST API. Traefik's API is
+// read-only; when a file provider directory is configured, config is
+// written there instead and Traefik's file watcher applies it.
 type TraefikAdapter struct {
 	serverID   string
 	serverName string
 	apiURL     string
 	apiToken   string
 	httpClient *http.Client
+	metricsURL string
+	files      traefikFiles // nil when read-only
+	fileName   string
+	logs       *logSource // nil when no log source is set
 }
 
-func NewTraefikAdapter(serverID, serverName, apiURL, apiToken string) *TraefikAdapter {
+func NewTraefikAdapter(serverID, serverName, apiURL, apiToken string, settings models.TraefikSettings, files traefikFiles, logs *logSource) *TraefikAdapter {
+	apiURL = strings.TrimRight(apiURL, "/")
+	metricsURL := settings.MetricsURL
+	if metricsURL == "" {
+		metricsURL = apiURL + "/metrics"
+	}
+	fileName := settings.FileName
+	if fileName == "" {
+		fileName = defaultTraefikFile
+	}
 	return &TraefikAdapter{
 		serverID:   serverID,
 		serverName: serverName,
-		apiURL:     strings.TrimRight(apiURL, "/"),
+		apiURL:     apiURL,
 		apiToken:   apiToken,
 		httpClient: &http.Client{Timeout: 10 * time.Second},
+		metricsURL: metricsURL,
+		files:      files,
+		fileName:   fileName,
+		logs:       logs,
 	}
 }
 
 func (a *TraefikAdapter) Type() string { return "traefik" }
 
 func (a *TraefikAdapter) doGet(ctx context.Context, path string) ([]byte, int, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", a.apiURL+path, nil)
+	return a.fetch(ctx, a.apiURL+path)
+}
+
+func (a *TraefikAdapter) fetch(ctx context.Context, u string) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -62,52 +84,140 @@ func (a *TraefikAdapter) Ping(ctx context.Context) (int64, error) {
 	return time.Since(start).Milliseconds(), nil
 }
 
+// GetMetrics combines the entrypoint metrics from Traefik's Prometheus
+// endpoint with the router, service and middleware counts in
+// /api/overview. A Traefik without Prometheus metrics enabled still
+// reports the counts.
 func (a *TraefikAdapter) GetMetrics(ctx context.Context) (*models.ServerMetrics, error) {
-	body, _, err := a.doGet(ctx, "/api/overview")
+	body, status, err := a.doGet(ctx, "/api/overview")
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("overview returned %d", status)
+	}
+	resources, err := parseTraefikOverview(body)
 	if err != nil {
 		return nil, err
 	}
 
-	var overview struct {
-		HTTP struct {
-			Routers  struct{ Total int } `json:"routers"`
-			Services struct{ Total int } `json:"services"`
-		} `json:"http"`
+	pm := promMetrics{}
+	body, status, err = a.fetch(ctx, a.metricsURL)
+	switch {
+	case err != nil:
+		return nil, err
+	case status == http.StatusOK:
+		if pm, err = parsePrometheus(string(body)); err != nil {
+			return nil, fmt.Errorf("parse traefik metrics: %w", err)
+		}
+	case status != http.StatusNotFound:
+		return nil, fmt.Errorf("metrics returned %d", status)
 	}
-	json.Unmarshal(body, &overview) //nolint:errcheck
 
-	return &models.ServerMetrics{
-		ServerID:  a.serverID,
-		Timestamp: time.Now(),
-	}, nil
+	m := traefikMetrics(a.serverID, pm)
+	m.Resources = resources
+	return m, nil
 }
 
+// GetConfig returns the file Proxera manages when a file provider is set
+// up, so that what is read back can be written again; otherwise the
+// routing configuration Traefik reports in /api/rawdata.
 func (a *TraefikAdapter) GetConfig(ctx context.Context) (*models.ProxyConfig, error) {
-	body, _, err := a.doGet(ctx, "/api/rawdata")
-	if err != nil {
-		return nil, err
-	}
-	return &models.ProxyConfig{
+	cfg := &models.ProxyConfig{
 		ServerID:     a.serverID,
 		ServerName:   a.serverName,
 		ProxyType:    models.ProxyTraefik,
-		Content:      string(body),
 		Format:       "yaml",
 		LastModified: time.Now().Format(time.RFC3339),
 		IsValid:      true,
-	}, nil
+	}
+
+	if a.files != nil {
+		content, err := a.files.read(ctx, a.fileName)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", a.fileName, err)
+		}
+		cfg.Content = content
+		if strings.HasSuffix(a.fileName, ".toml") {
+			cfg.Format = "toml"
+		}
+		return cfg, nil
+	}
+
+	body, _, err := a.doGet(ctx, "/api/rawdata")
+	if err != nil {
+		return nil, err
+	}
+	cfg.Content = string(body)
+	return cfg, nil
 }
 
-func (a *TraefikAdapter) PutConfig(_ context.Context, _ string) (*models.ConfigValidation, error) {
-	return nil, &ErrNotSupported{Op: "PutConfig"}
+// PutConfig validates content, writes it to the file provider directory
+// and waits for Traefik to load it. If Traefik never loads it or reports
+// errors for it, the previous file is put back.
+func (a *TraefikAdapter) PutConfig(ctx context.Context, content string) (*models.ConfigValidation, error) {
+	if a.files == nil {
+		return nil, &ErrNotSupported{Op: "PutConfig (no file provider directory configured)"}
+	}
+
+	previous, err := a.files.read(ctx, a.fileName)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", a.fileName, err)
+	}
+	raw, err := a.rawData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	before := map[string][]string{}
+	if old, err := parseTraefikFile(a.fileName, previous); err == nil {
+		before = traefikFileNames(old)
+	}
+
+	if errs := validateTraefikFile(a.fileName, content, fileNamesElsewhere(raw, before)); len(errs) > 0 {
+		return &models.ConfigValidation{IsValid: false, Errors: errs}, nil
+	}
+	if err := a.files.write(ctx, a.fileName, content); err != nil {
+		return &models.ConfigValidation{IsValid: false, Errors: []string{fmt.Sprintf("write %s: %s", a.fileName, err)}}, nil
+	}
+
+	next, _ := parseTraefikFile(a.fileName, content)
+	want := traefikFileNames(next)
+	gone := map[string][]string{}
+	for kind, names := range before {
+		for _, n := range names {
+			if !contains(want[kind], n) {
+				gone[kind] = append(gone[kind], n)
+			}
+		}
+	}
+
+	problems := a.confirmFile(ctx, want, gone)
+	if len(problems) == 0 {
+		return &models.ConfigValidation{IsValid: true}, nil
+	}
+	var restoreErr error
+	if previous == "" {
+		restoreErr = a.files.remove(ctx, a.fileName)
+	} else {
+		restoreErr = a.files.write(ctx, a.fileName, previous)
+	}
+	if restoreErr != nil {
+		problems = append(problems, fmt.Sprintf("restore %s: %s", a.fileName, restoreErr))
+	}
+	return &models.ConfigValidation{IsValid: false, Errors: problems}, nil
 }
 
+// Reload has nothing to do with a file provider: Traefik applies the file
+// as soon as it changes, and PutConfig waits for that.
 func (a *TraefikAdapter) Reload(_ context.Context) error {
-	return &ErrNotSupported{Op: "Reload"}
+	if a.files == nil {
+		return &ErrNotSupported{Op: "Reload"}
+	}
+	return nil
 }
 
-func (a *TraefikAdapter) TailLogs(_ context.Context) (io.ReadCloser, error) {
-	return nil, &ErrNotSupported{Op: "TailLogs"}
+func (a *TraefikAdapter) TailLogs(ctx context.Context) (io.ReadCloser, error) {
+	return tailLogs(ctx, a.logs)
 }
 
 func (a *TraefikAdapter) GetStatus(ctx context.Context) (string, error) {
@@ -117,3 +227,12 @@ func (a *TraefikAdapter) GetStatus(ctx context.Context) (string, error) {
 	}
 	return "online", nil
 }
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,470 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anveesa/proxera/models"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultTraefikFile = "proxera.yml"
+
+	// How long Traefik's file watcher gets to pick up a write.
+	traefikConfirmTimeout  = 15 * time.Second
+	traefikConfirmInterval = 500 * time.Millisecond
+)
+
+// traefikFiles reads and atomically replaces files in a Traefik file
+// provider directory.
+type traefikFiles interface {
+	// read returns "" for a file that does not exist.
+	read(ctx context.Context, name string) (string, error)
+	write(ctx context.Context, name, content string) error
+	remove(ctx context.Context, name string) error
+}
+
+// newTraefikFiles returns the file access the settings ask for, or nil if
+// the server has no file provider directory. A local directory must be
+// under localRoot, or the server is read-only.
+func newTraefikFiles(serverID, host string, sshCfg SSHConfig, settings models.TraefikSettings, localRoot string, pool *SSHPool) traefikFiles {
+	if settings.FileDir == "" {
+		return nil
+	}
+	if settings.FileAccess == models.FileAccessLocal {
+		if !InsideDir(localRoot, settings.FileDir) {
+			return nil
+		}
+		return localTraefikFiles{dir: settings.FileDir}
+	}
+	port := settings.SSHPort
+	if port == 0 {
+		port = 22
+	}
+	return &sshTraefikFiles{
+		serverID: serverID,
+		host:     host,
+		port:     port,
+		ssh:      sshCfg,
+		sudo:     settings.UseSudo == nil || *settings.UseSudo,
+		dir:      settings.FileDir,
+		pool:     pool,
+	}
+}
+
+// sshTraefikFiles reaches the directory over SSH.
+type sshTraefikFiles struct {
+	serverID string
+	host     string
+	port     int
+	ssh      SSHConfig
+	sudo     bool
+	dir      string
+	pool     *SSHPool
+}
+
+func (f *sshTraefikFiles) run(ctx context.Context, script, stdin string) (string, error) {
+	client, err := f.pool.Get(ctx, f.serverID, f.host, f.port, f.ssh)
+	if err != nil {
+		return "", fmt.Errorf("ssh connect: %w", err)
+	}
+	out, err := runCommand(client, wrapCommand(script, f.sudo, ""), strings.NewReader(stdin))
+	if err != nil {
+		return "", errors.New(commandError(out, err))
+	}
+	return out, nil
+}
+
+func (f *sshTraefikFiles) read(ctx context.Context, name string) (string, error) {
+	p := shellQuote(path.Join(f.dir, name))
+	return f.run(ctx, fmt.Sprintf("[ ! -e %[1]s ] || cat %[1]s", p), "")
+}
+
+func (f *sshTraefikFiles) write(ctx context.Context, name, content string) error {
+	target := path.Join(f.dir, name)
+	out, err := f.run(ctx, stageScript(target, target+stageSuffix+"-XXXXXX"), content)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	tmp := lines[len(lines)-1]
+	if !strings.HasPrefix(tmp, target+stageSuffix+"-") {
+		return fmt.Errorf("unexpected output: %s", out)
+	}
+	_, err = f.run(ctx, "mv -f "+shellQuote(tmp)+" "+shellQuote(target), "")
+	return err
+}
+
+func (f *sshTraefikFiles) remove(ctx context.Context, name string) error {
+	_, err := f.run(ctx, "rm -f "+shellQuote(path.Join(f.dir, name)), "")
+	return err
+}
+
+// localTraefikFiles writes to a directory Proxera shares with Traefik, such
+// as a common volume.
+type localTraefikFiles struct {
+	dir string
+}
+
+func (f localTraefikFiles) read(_ context.Context, name string) (string, error) {
+	b, err := os.ReadFile(filepath.Join(f.dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	return string(b), err
+}
+
+func (f localTraefikFiles) write(_ context.Context, name, content string) error {
+	target := filepath.Join(f.dir, name)
+	mode := fs.FileMode(0o644)
+	if fi, err := os.Stat(target); err == nil {
+		mode = fi.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(f.dir, name+stageSuffix+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // gone after a successful rename
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+func (f localTraefikFiles) remove(_ context.Context, name string) error {
+	err := os.Remove(filepath.Join(f.dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// InsideDir reports whether dir is root or below it, after resolving
+// symlinks in both. It is false for an empty root.
+func InsideDir(root, dir string) bool {
+	if root == "" || !filepath.IsAbs(root) || !filepath.IsAbs(dir) {
+		return false
+	}
+	resolve := func(p string) string {
+		if r, err := filepath.EvalSymlinks(p); err == nil {
+			return r
+		}
+		return filepath.Clean(p)
+	}
+	rel, err := filepath.Rel(resolve(root), resolve(dir))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// ─── Validation ───────────────────────────────────────────────────────────────
+
+// traefikFileConfig is the top level of a dynamic configuration file.
+// Decoding rejects unknown keys at this level and within each section;
+// routers, services and middlewares are checked by hand so that options
+// added by newer Traefik releases are not refused.
+type traefikFileConfig struct {
+	HTTP *traefikFileSection `yaml:"http" toml:"http"`
+	TCP  *traefikFileSection `yaml:"tcp" toml:"tcp"`
+	UDP  *traefikFileSection `yaml:"udp" toml:"udp"`
+	TLS  map[string]any      `yaml:"tls" toml:"tls"`
+}
+
+type traefikFileSection struct {
+	Routers           map[string]map[string]any `yaml:"routers" toml:"routers"`
+	Services          map[string]map[string]any `yaml:"services" toml:"services"`
+	Middlewares       map[string]map[string]any `yaml:"middlewares" toml:"middlewares"`
+	ServersTransports map[string]any            `yaml:"serversTransports" toml:"serversTransports"`
+}
+
+// parseTraefikFile decodes content as YAML, or TOML for a .toml file name.
+func parseTraefikFile(name, content string) (*traefikFileConfig, error) {
+	var cfg traefikFileConfig
+	if strings.HasSuffix(name, ".toml") {
+		dec := toml.NewDecoder(strings.NewReader(content))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&cfg); err != nil {
+			var strict *toml.StrictMissingError
+			if errors.As(err, &strict) {
+				return nil, errors.New(strict.String())
+			}
+			return nil, err
+		}
+		return &cfg, nil
+	}
+	dec := yaml.NewDecoder(bytes.NewReader([]byte(content)))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// validateTraefikFile checks what Traefik would otherwise only report
+// after loading the file: routers without a rule or service, references to
+// services or middlewares that are defined neither in the file nor in
+// elsewhere (per elsewhere, keyed like traefikFileNames; names qualified
+// with another provider, as in "auth@docker", are not checked), and
+// services without servers.
+func validateTraefikFile(name, content string, elsewhere map[string][]string) []string {
+	cfg, err := parseTraefikFile(name, content)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	defined := func(sec *traefikFileSection, proto, kind, ref string) bool {
+		if strings.HasSuffix(ref, "@file") {
+			ref = strings.TrimSuffix(ref, "@file")
+		} else if strings.Contains(ref, "@") {
+			return true
+		}
+		items := sec.Services
+		if kind == "middlewares" {
+			items = sec.Middlewares
+		}
+		return items[ref] != nil || contains(elsewhere[rawKind(proto, kind)], ref+"@file")
+	}
+
+	var errs []string
+	check := func(proto string, sec *traefikFileSection, needsRule bool, serverKey string) {
+		if sec == nil {
+			return
+		}
+		for _, rn := range sortedKeys(sec.Routers) {
+			r := sec.Routers[rn]
+			if needsRule && stringField(r, "rule") == "" {
+				errs = append(errs, fmt.Sprintf("%s router %q: rule is required", proto, rn))
+			}
+			svc := stringField(r, "service")
+			switch {
+			case svc == "":
+				errs = append(errs, fmt.Sprintf("%s router %q: service is required", proto, rn))
+			case !defined(sec, proto, "services", svc):
+				errs = append(errs, fmt.Sprintf("%s router %q: service %q is not defined", proto, rn, svc))
+			}
+			mws, _ := r["middlewares"].([]any)
+			for _, m := range mws {
+				mw, _ := m.(string)
+				if !defined(sec, proto, "middlewares", mw) {
+					errs = append(errs, fmt.Sprintf("%s router %q: middleware %q is not defined", proto, rn, mw))
+				}
+			}
+		}
+		for _, sn := range sortedKeys(sec.Services) {
+			s := sec.Services[sn]
+			if len(s) != 1 {
+				errs = append(errs, fmt.Sprintf("%s service %q: must define exactly one service type", proto, sn))
+				continue
+			}
+			lb, ok := s["loadBalancer"].(map[string]any)
+			if !ok {
+				continue
+			}
+			servers, _ := lb["servers"].([]any)
+			if len(servers) == 0 {
+				errs = append(errs, fmt.Sprintf("%s service %q: loadBalancer has no servers", proto, sn))
+			}
+			for _, srv := range servers {
+				m, _ := srv.(map[string]any)
+				v := stringField(m, serverKey)
+				if v == "" {
+					errs = append(errs, fmt.Sprintf("%s service %q: every server needs a %s", proto, sn, serverKey))
+				} else if serverKey == "url" {
+					if u, err := url.Parse(v); err != nil || u.Host == "" {
+						errs = append(errs, fmt.Sprintf("%s service %q: invalid server url %q", proto, sn, v))
+					}
+				}
+			}
+		}
+		for _, mn := range sortedKeys(sec.Middlewares) {
+			if len(sec.Middlewares[mn]) != 1 {
+				errs = append(errs, fmt.Sprintf("%s middleware %q: must define exactly one middleware type", proto, mn))
+			}
+		}
+	}
+	check("http", cfg.HTTP, true, "url")
+	check("tcp", cfg.TCP, true, "address")
+	check("udp", cfg.UDP, false, "address")
+	return errs
+}
+
+// traefikFileNames lists what a file defines, keyed the way /api/rawdata
+// groups it ("routers", "tcpServices", ...), with the @file suffix Traefik
+// gives names from the file provider.
+func traefikFileNames(cfg *traefikFileConfig) map[string][]string {
+	names := map[string][]string{}
+	add := func(proto string, sec *traefikFileSection) {
+		if sec == nil {
+			return
+		}
+		for kind, items := range map[string]map[string]map[string]any{
+			"routers":     sec.Routers,
+			"services":    sec.Services,
+			"middlewares": sec.Middlewares,
+		} {
+			for n := range items {
+				names[rawKind(proto, kind)] = append(names[rawKind(proto, kind)], n+"@file")
+			}
+		}
+	}
+	add("http", cfg.HTTP)
+	add("tcp", cfg.TCP)
+	add("udp", cfg.UDP)
+	return names
+}
+
+// rawKind names a section the way /api/rawdata does: "services" for HTTP,
+// "tcpServices" for TCP and so on.
+func rawKind(proto, kind string) string {
+	if proto == "http" {
+		return kind
+	}
+	return proto + strings.ToUpper(kind[:1]) + kind[1:]
+}
+
+// ─── Confirmation ─────────────────────────────────────────────────────────────
+
+// traefikRawKinds are the sections of /api/rawdata.
+var traefikRawKinds = []string{
+	"routers", "services", "middlewares",
+	"tcpRouters", "tcpServices", "tcpMiddlewares",
+	"udpRouters", "udpServices",
+}
+
+// traefikRawItem is the part of a /api/rawdata entry we look at.
+type traefikRawItem struct {
+	Status string   `json:"status"`
+	Error  []string `json:"error"`
+}
+
+// rawData fetches /api/rawdata as items by section and name.
+func (a *TraefikAdapter) rawData(ctx context.Context) (map[string]map[string]traefikRawItem, error) {
+	body, status, err := a.doGet(ctx, "/api/rawdata")
+	if err != nil {
+		return nil, err
+	}
+	if status != 200 {
+		return nil, fmt.Errorf("/api/rawdata returned %d", status)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+	out := make(map[string]map[string]traefikRawItem, len(traefikRawKinds))
+	for _, kind := range traefikRawKinds {
+		var items map[string]traefikRawItem
+		if len(raw[kind]) > 0 {
+			if err := json.Unmarshal(raw[kind], &items); err != nil {
+				return nil, fmt.Errorf("/api/rawdata %s: %w", kind, err)
+			}
+		}
+		out[kind] = items
+	}
+	return out, nil
+}
+
+// fileNamesElsewhere lists the @file items Traefik has loaded that are not
+// among ours, the names our own file defines: those from other files in the
+// provider directory.
+func fileNamesElsewhere(raw map[string]map[string]traefikRawItem, ours map[string][]string) map[string][]string {
+	out := map[string][]string{}
+	for kind, items := range raw {
+		for n := range items {
+			if strings.HasSuffix(n, "@file") && !contains(ours[kind], n) {
+				out[kind] = append(out[kind], n)
+			}
+		}
+	}
+	return out
+}
+
+// confirmFile polls /api/rawdata until Traefik has loaded what the new file
+// defines and dropped what only the old one did. It returns the problems
+// Traefik reported for the file's items, or why it gave up waiting.
+func (a *TraefikAdapter) confirmFile(ctx context.Context, want, gone map[string][]string) []string {
+	ctx, cancel := context.WithTimeout(ctx, traefikConfirmTimeout)
+	defer cancel()
+
+	var pending []string
+	for {
+		if raw, err := a.rawData(ctx); err == nil {
+			var problems []string
+			pending, problems = compareRawData(raw, want, gone)
+			if len(pending) == 0 {
+				return problems
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			msg := fmt.Sprintf("Traefik did not load the change within %s", traefikConfirmTimeout)
+			if len(pending) > 0 {
+				msg += " (waiting on " + strings.Join(pending, ", ") + ")"
+			}
+			return []string{msg}
+		case <-time.After(traefikConfirmInterval):
+		}
+	}
+}
+
+// compareRawData reports which expected items are not in raw yet (or not
+// gone yet), and the errors Traefik recorded against the expected ones.
+func compareRawData(raw map[string]map[string]traefikRawItem, want, gone map[string][]string) (pending, problems []string) {
+	for kind, names := range want {
+		for _, n := range names {
+			item, ok := raw[kind][n]
+			if !ok {
+				pending = append(pending, n)
+				continue
+			}
+			for _, e := range item.Error {
+				problems = append(problems, fmt.Sprintf("%s %s: %s", kind, n, e))
+			}
+		}
+	}
+	for kind, names := range gone {
+		for _, n := range names {
+			if _, ok := raw[kind][n]; ok {
+				pending = append(pending, n)
+			}
+		}
+	}
+	sort.Strings(pending)
+	sort.Strings(problems)
+	return pending, problems
+}
+
+func stringField(m map[string]any, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,149 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anveesa/proxera/models"
+)
+
+const (
+	traefikRequests = "traefik_entrypoint_requests_total"
+	traefikDuration = "traefik_entrypoint_request_duration_seconds"
+
+	// traefikInternalEntrypoint serves the API, dashboard and metrics; its
+	// traffic is mostly Proxera's own polling, so it is left out.
+	traefikInternalEntrypoint = "traefik"
+)
+
+// traefikMetrics derives server metrics, with one detail per entrypoint,
+// from Traefik's entrypoint metrics. Rates, error rates and latencies
+// cover the time since the previous scrape; on the first one there are no
+// rates and the others cover Traefik's lifetime.
+func traefikMetrics(serverID string, pm promMetrics) *models.ServerMetrics {
+	m := &models.ServerMetrics{
+		ServerID:  serverID,
+		Timestamp: time.Now(),
+	}
+
+	// Open connections are traefik_entrypoint_open_connections in v2 and
+	// traefik_open_connections (also kept per entrypoint) in v3.
+	open := pm["traefik_entrypoint_open_connections"]
+	if open == nil {
+		open = pm["traefik_open_connections"]
+	}
+	conns := map[string]float64{}
+	for _, s := range open {
+		if ep := s.labels["entrypoint"]; ep != "" {
+			conns[ep] += s.value
+		}
+	}
+
+	names := map[string]bool{}
+	for _, s := range pm[traefikRequests] {
+		names[s.labels["entrypoint"]] = true
+	}
+	for ep := range conns {
+		names[ep] = true
+	}
+	delete(names, traefikInternalEntrypoint)
+	delete(names, "")
+	entrypoints := sortedKeys(names)
+
+	counters := map[string]float64{}
+	for _, ep := range entrypoints {
+		key := "ep/" + ep + "/"
+		of := func(l map[string]string) bool { return l["entrypoint"] == ep }
+		counters[key+"requests"] = pm.sum(traefikRequests, of)
+		counters[key+"5xx"] = pm.sum(traefikRequests, func(l map[string]string) bool {
+			return of(l) && strings.HasPrefix(l["code"], "5")
+		})
+		counters[key+"bytesIn"] = pm.sum("traefik_entrypoint_requests_bytes_total", of)
+		counters[key+"bytesOut"] = pm.sum("traefik_entrypoint_responses_bytes_total", of)
+		counters[key+"durationSum"] = pm.sum(traefikDuration+"_sum", of)
+		counters[key+"durationCount"] = pm.sum(traefikDuration+"_count", of)
+	}
+	addBuckets(counters, "duration:", pm.buckets(traefikDuration, func(l map[string]string) bool {
+		return l["entrypoint"] != traefikInternalEntrypoint
+	}))
+
+	window := counters
+	deltas, seconds, haveDeltas := counterDeltas(serverID, counters)
+	m.Cumulative = !haveDeltas
+	if haveDeltas {
+		window = deltas
+	}
+
+	var requests, errors float64
+	for _, ep := range entrypoints {
+		key := "ep/" + ep + "/"
+		d := models.MetricsDetail{
+			Kind:              "entrypoint",
+			Name:              ep,
+			ActiveConnections: int(conns[ep]),
+		}
+		if haveDeltas {
+			d.RequestsPerSec = window[key+"requests"] / seconds
+			d.NetworkIn = window[key+"bytesIn"] / seconds
+			d.NetworkOut = window[key+"bytesOut"] / seconds
+		}
+		if n := window[key+"requests"]; n > 0 {
+			d.ErrorRate = window[key+"5xx"] / n * 100
+		}
+		if n := window[key+"durationCount"]; n > 0 {
+			d.AvgResponseTime = window[key+"durationSum"] / n * 1000
+		}
+
+		m.RequestsPerSec += d.RequestsPerSec
+		m.ActiveConnections += d.ActiveConnections
+		m.NetworkIn += d.NetworkIn
+		m.NetworkOut += d.NetworkOut
+		requests += window[key+"requests"]
+		errors += window[key+"5xx"]
+		m.Details = append(m.Details, d)
+	}
+	if requests > 0 {
+		m.ErrorRate = errors / requests * 100
+	}
+	setLatencyPercentiles(m, bucketsFrom(window, "duration:"))
+	return m
+}
+
+// parseTraefikOverview reads the object counts in /api/overview, which
+// groups them by protocol and kind:
+// {"http": {"routers": {"total": 3, "warnings": 0, "errors": 1}, ...}, ...}
+func parseTraefikOverview(body []byte) ([]models.ResourceSummary, error) {
+	type counts struct {
+		Total    int `json:"total"`
+		Warnings int `json:"warnings"`
+		Errors   int `json:"errors"`
+	}
+	var overview struct {
+		HTTP map[string]counts `json:"http"`
+		TCP  map[string]counts `json:"tcp"`
+		UDP  map[string]counts `json:"udp"`
+	}
+	if err := json.Unmarshal(body, &overview); err != nil {
+		return nil, fmt.Errorf("decode overview: %w", err)
+	}
+
+	var out []models.ResourceSummary
+	for _, p := range []struct {
+		name  string
+		kinds map[string]counts
+	}{{"http", overview.HTTP}, {"tcp", overview.TCP}, {"udp", overview.UDP}} {
+		for _, kind := range sortedKeys(p.kinds) {
+			c := p.kinds[kind]
+			out = append(out, models.ResourceSummary{
+				Protocol: p.name,
+				Kind:     kind,
+				Total:    c.Total,
+				Warnings: c.Warnings,
+				Errors:   c.Errors,
+			})
+		}
+	}
+	return out, nil
+}